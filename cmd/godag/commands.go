@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+	"github.com/opengeektech/go-dag/graph/graphview"
+)
+
+func cmdValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	strict := fs.Bool("strict", false, "reject unknown fields")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "godag validate: no input files")
+		return 2
+	}
+	code := 0
+	for _, path := range fs.Args() {
+		graphs, err := loadGraphs(path, *strict)
+		if err != nil {
+			printError(stderr, path, err)
+			code = 1
+			continue
+		}
+		nodes := 0
+		for _, g := range graphs {
+			nodes += len(g.NodeIdMapping)
+		}
+		fmt.Fprintf(stdout, "%s: ok, %d graphs, %d nodes\n", path, len(graphs), nodes)
+	}
+	return code
+}
+
+func cmdRender(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("render", stderr)
+	format := fs.String("format", "dot", "output format: dot, mermaid, plantuml, svg, html")
+	name := fs.String("graph", "", "graph name when the file contains several graphs")
+	dir := fs.String("dir", "TB", "direction: TB, BT, LR, RL")
+	group := fs.String("group", "", "group nodes by this attribute")
+	out := fs.String("o", "", "output file, default stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "godag render: expect one input file")
+		return 2
+	}
+	direction := graphview.Direction(strings.ToUpper(*dir))
+	switch direction {
+	case graphview.TopBottom, graphview.BottomTop, graphview.LeftRight, graphview.RightLeft:
+	default:
+		fmt.Fprintf(stderr, "godag render: unknown direction %s, expect TB, BT, LR or RL\n", *dir)
+		return 2
+	}
+	g, err := loadGraph(fs.Arg(0), *name)
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	opts := []graphview.RenderOption{graphview.WithDirection(direction)}
+	if *group != "" {
+		opts = append(opts, graphview.WithGroupAttr(*group))
+	}
+	renderers := map[string]func(*graph.Graph, ...graphview.RenderOption) (string, error){
+		"dot":      graphview.GetDotGraphDesc,
+		"mermaid":  graphview.GetMermaidGraphDesc,
+		"plantuml": graphview.GetPlantUMLGraphDesc,
+		"svg":      graphview.GetSvgGraphDesc,
+		"html":     graphview.GetHtmlGraphDesc,
+	}
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "godag render: unknown format %s\n", *format)
+		return 2
+	}
+	s, err := render(g, opts...)
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	if *out == "" {
+		fmt.Fprint(stdout, s)
+		return 0
+	}
+	if err := os.WriteFile(*out, []byte(s), 0o644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+type planOutput struct {
+	Graph  string     `json:"graph"`
+	Levels [][]string `json:"levels"`
+	Depth  int        `json:"depth"`
+	Width  int        `json:"width"`
+}
+
+func cmdPlan(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("plan", stderr)
+	name := fs.String("graph", "", "graph name when the file contains several graphs")
+	asJson := fs.Bool("json", false, "print json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "godag plan: expect one input file")
+		return 2
+	}
+	g, err := loadGraph(fs.Arg(0), *name)
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	levels, err := g.Levels()
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	plan := planOutput{Graph: g.GraphName, Depth: len(levels)}
+	for _, level := range levels {
+		var names []string
+		for _, n := range level {
+			names = append(names, n.Name)
+		}
+		sort.Strings(names)
+		plan.Levels = append(plan.Levels, names)
+		plan.Width = max(plan.Width, len(names))
+	}
+	if *asJson {
+		return printJson(stdout, stderr, plan)
+	}
+	for i, level := range plan.Levels {
+		fmt.Fprintf(stdout, "level %d (%d): %s\n", i+1, len(level), strings.Join(level, ", "))
+	}
+	fmt.Fprintf(stdout, "depth %d, max parallel width %d\n", plan.Depth, plan.Width)
+	return 0
+}
+
+func cmdStats(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("stats", stderr)
+	name := fs.String("graph", "", "graph name when the file contains several graphs")
+	asJson := fs.Bool("json", false, "print json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "godag stats: expect one input file")
+		return 2
+	}
+	g, err := loadGraph(fs.Arg(0), *name)
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	st, err := g.Stats()
+	if err != nil {
+		printError(stderr, fs.Arg(0), err)
+		return 1
+	}
+	if *asJson {
+		return printJson(stdout, stderr, st)
+	}
+	fmt.Fprintf(stdout, "nodes:       %d\n", st.Nodes)
+	fmt.Fprintf(stdout, "edges:       %d\n", st.Edges)
+	fmt.Fprintf(stdout, "depth:       %d\n", st.Depth)
+	fmt.Fprintf(stdout, "width:       %d\n", st.Width)
+	fmt.Fprintf(stdout, "max fan-in:  %d\n", st.MaxFanIn)
+	fmt.Fprintf(stdout, "max fan-out: %d\n", st.MaxFanOut)
+	fmt.Fprintf(stdout, "roots:       %s\n", strings.Join(st.Roots, ", "))
+	fmt.Fprintf(stdout, "leaves:      %s\n", strings.Join(st.Leaves, ", "))
+	return 0
+}
+
+func cmdDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", stderr)
+	name := fs.String("graph", "", "graph name when the files contain several graphs")
+	asJson := fs.Bool("json", false, "print json")
+	exitCode := fs.Bool("exit-code", false, "exit with 1 when the graphs differ")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "godag diff: expect old and new file")
+		return 2
+	}
+	var graphs [2]*graph.Graph
+	for i, path := range fs.Args() {
+		g, err := loadGraph(path, *name)
+		if err != nil {
+			printError(stderr, path, err)
+			return 1
+		}
+		graphs[i] = g
+	}
+	d := graph.Diff(graphs[0], graphs[1])
+	if *asJson {
+		if code := printJson(stdout, stderr, d); code != 0 {
+			return code
+		}
+	} else {
+		fmt.Fprint(stdout, d.Text())
+	}
+	if *exitCode && !d.Empty() {
+		return 1
+	}
+	return 0
+}
+
+func printJson(stdout, stderr io.Writer, v any) int {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
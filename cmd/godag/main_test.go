@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func runCmd(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestValidate(t *testing.T) {
+	code, out, _ := runCmd("validate", "-strict", "testdata/v1.json", "testdata/v2.yaml", "testdata/flow.dot")
+	if code != 0 || strings.Count(out, ": ok") != 3 {
+		t.Fatal(code, out)
+	}
+	code, _, errOut := runCmd("validate", "testdata/broken.json")
+	if code != 1 || strings.Count(errOut, "\n") != 3 {
+		t.Fatal(code, errOut)
+	}
+	t.Log("\n" + errOut)
+}
+
+func TestRender(t *testing.T) {
+	for _, format := range []string{"dot", "mermaid", "plantuml", "svg", "html"} {
+		code, out, errOut := runCmd("render", "-format", format, "-dir", "lr", "testdata/v1.json")
+		if code != 0 || !strings.Contains(out, "join") {
+			t.Fatal(format, code, errOut)
+		}
+	}
+	if code, _, _ := runCmd("render", "-format", "png", "testdata/v1.json"); code != 2 {
+		t.Error("expect usage error")
+	}
+	if code, _, errOut := runCmd("render", "-dir", "sideways", "testdata/v1.json"); code != 2 || !strings.Contains(errOut, "unknown direction") {
+		t.Error("expect usage error", errOut)
+	}
+}
+
+func TestPlanAndStats(t *testing.T) {
+	code, out, _ := runCmd("plan", "testdata/v1.json")
+	expect := `level 1 (1): start
+level 2 (2): orders, users
+level 3 (1): join
+depth 3, max parallel width 2
+`
+	if code != 0 || out != expect {
+		t.Fatalf("%d\n%s", code, out)
+	}
+	code, out, _ = runCmd("stats", "-json", "testdata/flow.dot")
+	var st struct {
+		Nodes, Edges, Depth, Width, MaxFanIn, MaxFanOut int
+	}
+	if err := json.Unmarshal([]byte(out), &st); err != nil || code != 0 {
+		t.Fatal(code, err)
+	}
+	if st.Nodes != 4 || st.Edges != 4 || st.Depth != 3 || st.Width != 2 || st.MaxFanIn != 2 || st.MaxFanOut != 2 {
+		t.Fatalf("%+v", st)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	code, out, _ := runCmd("diff", "-exit-code", "testdata/v1.json", "testdata/v2.yaml")
+	if code != 1 {
+		t.Fatal("expect differences ", code)
+	}
+	for _, expect := range []string{
+		"+ node report", "- node users", "- edge users -> join", "+ edge start -> report",
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("missing %s in\n%s", expect, out)
+		}
+	}
+	code, out, _ = runCmd("diff", "-json", "testdata/v1.json", "testdata/v2.yaml")
+	var report graph.DiffReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil || code != 0 || len(report.AddedEdges) != 2 {
+		t.Fatal(code, err, out)
+	}
+	if code, out, _ := runCmd("diff", "-exit-code", "testdata/v1.json", "testdata/v1.json"); code != 0 || out != "" {
+		t.Fatal(code, out)
+	}
+}
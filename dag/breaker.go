@@ -0,0 +1,264 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBreakerOpen = errors.New("circuit breaker open")
+)
+
+// BreakerState 熔断器状态, 零值表示节点没有熔断器
+type BreakerState uint8
+
+const (
+	BreakerClosed BreakerState = iota + 1
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	}
+	return ""
+}
+
+// BreakerConfig 节点熔断配置, 零值字段使用默认值
+type BreakerConfig[K, V any] struct {
+	// FailureThreshold 连续失败多少次后打开, 默认 5
+	FailureThreshold int
+	// CoolDown 打开后经过多久进入半开, 默认 30s
+	CoolDown time.Duration
+	// HalfOpenMax 半开时允许同时试探的调用数, 默认 1
+	HalfOpenMax int
+	// SuccessThreshold 半开时连续成功多少次后关闭, 默认 1
+	SuccessThreshold int
+	// Fallback 拒绝调用时执行, 为 nil 时节点以 ErrBreakerOpen 失败
+	Fallback HandlerFunc[K, V]
+	// IsFailure 判断错误是否计入失败, 默认 context.Canceled 以外的错误都计入
+	IsFailure func(err error) bool
+}
+
+// BreakerStats 熔断器当前状态和累计计数
+type BreakerStats struct {
+	State    BreakerState
+	Failures int
+	OpenedAt time.Time
+	Trips    uint64
+	Rejected uint64
+}
+
+type breaker struct {
+	protect          sync.Mutex
+	failureThreshold int
+	coolDown         time.Duration
+	halfOpenMax      int
+	successThreshold int
+
+	state BreakerState
+	// generation 每次状态变化时加一, 用来忽略在之前状态中放行的调用结果
+	generation uint64
+	failures   int
+	successes  int
+	inFlight   int
+	openedAt   time.Time
+	trips      uint64
+	rejected   uint64
+}
+
+// breakerTicket 放行调用时的状态
+type breakerTicket struct {
+	state      BreakerState
+	generation uint64
+}
+
+type nodeBreaker[K, V any] struct {
+	*breaker
+	fallback  HandlerFunc[K, V]
+	isFailure func(err error) bool
+}
+
+// SetBreaker 为节点设置熔断器, 熔断器由该 Dag 的所有运行共享
+func (r *Dag[K, V]) SetBreaker(nodeName string, cfg BreakerConfig[K, V]) {
+	b := &breaker{
+		failureThreshold: cfg.FailureThreshold,
+		coolDown:         cfg.CoolDown,
+		halfOpenMax:      cfg.HalfOpenMax,
+		successThreshold: cfg.SuccessThreshold,
+		state:            BreakerClosed,
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = 5
+	}
+	if b.coolDown <= 0 {
+		b.coolDown = 30 * time.Second
+	}
+	if b.halfOpenMax <= 0 {
+		b.halfOpenMax = 1
+	}
+	if b.successThreshold <= 0 {
+		b.successThreshold = 1
+	}
+	nb := &nodeBreaker[K, V]{breaker: b, fallback: cfg.Fallback, isFailure: cfg.IsFailure}
+	if nb.isFailure == nil {
+		nb.isFailure = func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		}
+	}
+	breakers := make(map[string]*nodeBreaker[K, V])
+	w, _ := r.breakers.Load().(map[string]*nodeBreaker[K, V])
+	for k, v := range w {
+		breakers[k] = v
+	}
+	breakers[nodeName] = nb
+	r.breakers.Store(breakers)
+}
+
+func (r *Dag[K, V]) getBreakers() map[string]*nodeBreaker[K, V] {
+	w, _ := r.breakers.Load().(map[string]*nodeBreaker[K, V])
+	return w
+}
+
+// BreakerStates 返回各节点熔断器的状态, 可用于导出监控指标
+func (r *Dag[K, V]) BreakerStates() map[string]BreakerStats {
+	w := r.getBreakers()
+	ret := make(map[string]BreakerStats, len(w))
+	for name, b := range w {
+		ret[name] = b.stats()
+	}
+	return ret
+}
+
+func (b *breaker) stats() BreakerStats {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	b.refresh()
+	return BreakerStats{State: b.state, Failures: b.failures, OpenedAt: b.openedAt, Trips: b.trips, Rejected: b.rejected}
+}
+
+// setState 切换状态并重置计数, 调用方持有锁
+func (b *breaker) setState(state BreakerState) {
+	b.state = state
+	b.generation++
+	b.failures, b.successes, b.inFlight = 0, 0, 0
+	if state == BreakerOpen {
+		b.openedAt = time.Now()
+		b.trips++
+	}
+}
+
+// refresh 冷却结束后从打开进入半开, 调用方持有锁
+func (b *breaker) refresh() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.coolDown {
+		b.setState(BreakerHalfOpen)
+	}
+}
+
+// allow 返回是否允许调用以及放行时的状态
+func (b *breaker) allow() (bool, breakerTicket) {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	b.refresh()
+	ticket := breakerTicket{state: b.state, generation: b.generation}
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return false, ticket
+	case BreakerHalfOpen:
+		if b.inFlight >= b.halfOpenMax {
+			b.rejected++
+			return false, ticket
+		}
+		b.inFlight++
+	}
+	return true, ticket
+}
+
+// release 归还没有执行的调用占用的半开名额
+func (b *breaker) release(ticket breakerTicket) {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	if ticket.generation == b.generation && b.state == BreakerHalfOpen {
+		b.inFlight--
+	}
+}
+
+// done 记录调用结果, 状态已经变化后才结束的调用不计入
+func (b *breaker) done(ticket breakerTicket, failed bool) {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	if ticket.generation != b.generation {
+		return
+	}
+	if b.state == BreakerHalfOpen {
+		b.inFlight--
+	}
+	if failed {
+		b.failures++
+		// 半开时任何失败重新打开
+		if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+			b.setState(BreakerOpen)
+		}
+		return
+	}
+	b.failures = 0
+	if b.state == BreakerHalfOpen {
+		b.successes++
+		if b.successes >= b.successThreshold {
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+// breakerCall 一次节点执行中的熔断器检查
+type breakerCall[K, V any] struct {
+	nb       *nodeBreaker[K, V]
+	state    *BreakerState
+	fallback *bool
+	invoked  bool
+	failed   bool
+}
+
+// gate 在等待限流和资源之前检查熔断器, 打开时不再等待
+func (c *breakerCall[K, V]) gate(handler HandlerFunc[K, V]) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		ok, ticket := c.nb.allow()
+		*c.state = ticket.state
+		if !ok {
+			if c.nb.fallback == nil {
+				var e V
+				return e, ErrBreakerOpen
+			}
+			*c.fallback = true
+			return c.nb.fallback(ctx, s)
+		}
+		defer func() {
+			// 没有调用到处理函数(等待限流或资源失败)时只归还放行名额, 不计入失败
+			if c.invoked {
+				c.nb.done(ticket, c.failed)
+			} else {
+				c.nb.release(ticket)
+			}
+		}()
+		return handler(ctx, s)
+	}
+}
+
+// record 包装实际的处理函数, 记录结果是否计入失败
+func (c *breakerCall[K, V]) record(handler HandlerFunc[K, V]) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		c.invoked, c.failed = true, true
+		v, err := handler(ctx, s)
+		c.failed = err != nil && c.nb.isFailure(err)
+		return v, err
+	}
+}
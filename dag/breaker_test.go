@@ -0,0 +1,151 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" {
+				calls.Add(1)
+				if !healthy.Load() {
+					return 0, errors.New("unavailable")
+				}
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.SetBreaker("b", BreakerConfig[int, int]{FailureThreshold: 2, CoolDown: 30 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		res := d.RunAsyncResult(context.TODO(), 1)
+		if res.Err == nil || res.Nodes["b"].Breaker != BreakerClosed || res.Nodes["a"].Breaker != 0 {
+			t.Fatal(i, res.Err, res.Nodes["b"].Breaker)
+		}
+	}
+	st := d.BreakerStates()["b"]
+	if st.State != BreakerOpen || st.Trips != 1 {
+		t.Fatal(st)
+	}
+	// 打开后快速失败, 不再调用节点
+	res := d.RunSyncResult(context.TODO(), 1)
+	if !errors.Is(res.Err, ErrBreakerOpen) || res.Nodes["b"].Breaker != BreakerOpen || calls.Load() != 2 {
+		t.Fatal(res.Err, res.Nodes["b"].Breaker, calls.Load())
+	}
+
+	// 冷却后半开, 试探失败重新打开
+	time.Sleep(40 * time.Millisecond)
+	if st := d.BreakerStates()["b"]; st.State != BreakerHalfOpen {
+		t.Fatal(st.State)
+	}
+	if res := d.RunAsyncResult(context.TODO(), 1); res.Nodes["b"].Breaker != BreakerHalfOpen || res.Err == nil {
+		t.Fatal(res.Err)
+	}
+	if st := d.BreakerStates()["b"]; st.State != BreakerOpen || st.Trips != 2 || st.Rejected != 1 {
+		t.Fatal(st)
+	}
+
+	// 试探成功后关闭
+	time.Sleep(40 * time.Millisecond)
+	healthy.Store(true)
+	for _, want := range []BreakerState{BreakerHalfOpen, BreakerClosed} {
+		res := d.RunAsyncResult(context.TODO(), 1)
+		if res.Err != nil || res.Output != 3 || res.Nodes["b"].Breaker != want {
+			t.Fatal(res.Err, res.Nodes["b"].Breaker)
+		}
+	}
+}
+
+func TestBreaker_Fallback(t *testing.T) {
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" {
+				return 0, errors.New("unavailable")
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.SetBreaker("b", BreakerConfig[int, int]{
+		FailureThreshold: 1,
+		CoolDown:         time.Hour,
+		Fallback: func(ctx context.Context, s *State[int, int]) (int, error) {
+			return -1, nil
+		},
+	})
+	if res := d.RunAsyncResult(context.TODO(), 1); res.Err == nil || res.Nodes["b"].Fallback {
+		t.Fatal(res.Err)
+	}
+	res := d.RunAsyncResult(context.TODO(), 1)
+	if res.Err != nil || !res.Nodes["b"].Fallback || res.Nodes["b"].Status.String() != "succeeded" {
+		t.Fatal(res.Err, res.Nodes["b"])
+	}
+}
+
+func TestBreaker_StaleResult(t *testing.T) {
+	b := &breaker{failureThreshold: 1, coolDown: 10 * time.Millisecond, halfOpenMax: 1, successThreshold: 1, state: BreakerClosed}
+	_, slow := b.allow()
+	_, fast := b.allow()
+	b.done(fast, true)
+	time.Sleep(20 * time.Millisecond)
+	ok, probe := b.allow()
+	if !ok || probe.state != BreakerHalfOpen {
+		t.Fatal(probe)
+	}
+	// 关闭状态放行的调用在半开后才结束, 不能当作试探结果
+	b.done(slow, false)
+	if st := b.stats(); st.State != BreakerHalfOpen {
+		t.Fatal(st.State)
+	}
+	if ok, _ := b.allow(); ok {
+		t.Fatal("probe slot released by stale result")
+	}
+	b.done(probe, false)
+	if st := b.stats(); st.State != BreakerClosed || st.Trips != 1 {
+		t.Fatal(st)
+	}
+}
+
+func TestBreaker_FallbackNotCached(t *testing.T) {
+	var healthy atomic.Bool
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" && !healthy.Load() {
+				return 0, errors.New("unavailable")
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.SetBreaker("b", BreakerConfig[int, int]{
+		FailureThreshold: 1,
+		CoolDown:         20 * time.Millisecond,
+		Fallback: func(ctx context.Context, s *State[int, int]) (int, error) {
+			return -1, nil
+		},
+	})
+	d.SetCache("b", CacheConfig[int, int]{Cache: NewMemoryCache(10), TTL: time.Hour})
+	d.RunAsync(context.TODO(), 1)
+	if res := d.RunAsyncResult(context.TODO(), 1); !res.Nodes["b"].Fallback || res.Nodes["b"].Cache != CacheMiss {
+		t.Fatal(res.Nodes["b"])
+	}
+	// 恢复后得到新的结果, 而不是缓存的降级结果
+	healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	for _, want := range []CacheStatus{CacheMiss, CacheHit} {
+		res := d.RunAsyncResult(context.TODO(), 1)
+		if res.Err != nil || res.Output != 3 || res.Nodes["b"].Cache != want || res.Nodes["b"].Fallback {
+			t.Fatal(res.Err, res.Output, res.Nodes["b"].Cache)
+		}
+	}
+}
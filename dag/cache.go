@@ -0,0 +1,303 @@
+package dag
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var ErrCacheKeyLossy = errors.New("value not fully encoded by json")
+
+// Cache 节点输出的缓存, 值为编码后的字节. ttl 为 0 表示不过期
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// CacheStatus 节点在一次运行中是否使用了缓存
+type CacheStatus uint8
+
+const (
+	CacheNone CacheStatus = iota
+	CacheMiss
+	CacheHit
+)
+
+func (s CacheStatus) String() string {
+	switch s {
+	case CacheMiss:
+		return "miss"
+	case CacheHit:
+		return "hit"
+	}
+	return "none"
+}
+
+// CacheConfig 节点缓存配置
+type CacheConfig[K, V any] struct {
+	Cache Cache
+	// Key 由输入和依赖结果计算缓存键, 为 nil 时使用 DefaultCacheKey. 返回错误时本次不使用缓存.
+	// 输入含有未导出字段等 json 编码不到的数据时必须自定义 Key, 否则节点不会被缓存
+	Key func(s *State[K, V]) (string, error)
+	TTL time.Duration
+}
+
+// DefaultCacheKey 对输入, 依赖结果和信号数据的 json 编码取 sha256.
+// 数据含有未导出字段, `json:"-"` 字段或 json 不支持的类型时返回 ErrCacheKeyLossy,
+// 避免不同的输入得到相同的键
+func DefaultCacheKey[K, V any](s *State[K, V]) (string, error) {
+	v := struct {
+		Input   K
+		Depends map[string]V
+		Signal  any `json:",omitempty"`
+	}{s.Input, s.DependNodeResult, s.Signal}
+	if err := checkLossless(reflect.ValueOf(v), 0); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+const maxLosslessDepth = 1000
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// checkLossless 检查值能否被 json 完整编码, 自定义编码的类型视为完整.
+// 嵌套过深时按循环引用处理
+func checkLossless(v reflect.Value, depth int) error {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxLosslessDepth {
+		return fmt.Errorf("%w: nested too deep", ErrCacheKeyLossy)
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) ||
+		reflect.PointerTo(t).Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return checkLossless(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			// 未导出的内嵌结构体的导出字段会被提升编码
+			embedded := f.Anonymous && f.Type.Kind() == reflect.Struct
+			if !f.IsExported() && !embedded || f.Tag.Get("json") == "-" {
+				return fmt.Errorf("%w: %s.%s", ErrCacheKeyLossy, t, f.Name)
+			}
+			if err := checkLossless(v.Field(i), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		it := v.MapRange()
+		for it.Next() {
+			if err := checkLossless(it.Value(), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkLossless(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Errorf("%w: %s", ErrCacheKeyLossy, t)
+	}
+	return nil
+}
+
+// SetCache 把纯函数节点标记为可缓存, 相同的键直接返回缓存的输出而不执行处理函数.
+// 输出使用 json 编码, 处理函数返回错误时不缓存
+func (r *Dag[K, V]) SetCache(nodeName string, cfg CacheConfig[K, V]) {
+	caches := make(map[string]CacheConfig[K, V])
+	w, _ := r.caches.Load().(map[string]CacheConfig[K, V])
+	for k, v := range w {
+		caches[k] = v
+	}
+	caches[nodeName] = cfg
+	r.caches.Store(caches)
+}
+
+func (r *Dag[K, V]) getCaches() map[string]CacheConfig[K, V] {
+	w, _ := r.caches.Load().(map[string]CacheConfig[K, V])
+	return w
+}
+
+// cachedHandler fallback 为 true 时结果来自熔断降级, 不写入缓存
+func cachedHandler[K, V any](cfg CacheConfig[K, V], handler HandlerFunc[K, V], status *CacheStatus, fallback *bool) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		keyFn := cfg.Key
+		if keyFn == nil {
+			keyFn = DefaultCacheKey[K, V]
+		}
+		key, err := keyFn(s)
+		if err != nil {
+			return handler(ctx, s)
+		}
+		// 不同节点的键互不影响
+		key = s.CurrentNode.Name + ":" + key
+		if b, ok := cfg.Cache.Get(key); ok {
+			var v V
+			if json.Unmarshal(b, &v) == nil {
+				*status = CacheHit
+				return v, nil
+			}
+		}
+		*status = CacheMiss
+		v, err := handler(ctx, s)
+		if err != nil || *fallback {
+			return v, err
+		}
+		if b, err := json.Marshal(v); err == nil {
+			cfg.Cache.Set(key, b, cfg.TTL)
+		}
+		return v, nil
+	}
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache 按最近使用淘汰的内存缓存
+type MemoryCache struct {
+	protect  sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+// NewMemoryCache capacity 为最多保存的条目数, 小于等于 0 时不限制
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{capacity: capacity, items: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	e := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(e)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*memoryEntry).key)
+	}
+}
+
+func (c *MemoryCache) Len() int {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	return c.order.Len()
+}
+
+// DiskCache 每个键保存为目录中的一个文件, 文件头 8 字节为过期时间
+type DiskCache struct {
+	dir string
+}
+
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil || len(b) < 8 {
+		return nil, false
+	}
+	if expires := int64(binary.BigEndian.Uint64(b)); expires != 0 && time.Now().UnixNano() > expires {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	return b[8:], true
+}
+
+// Set 先写临时文件再重命名, 并发读不会读到一半的内容
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) {
+	b := make([]byte, 8, 8+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(b, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	b = append(b, value...)
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+var (
+	_ Cache = &MemoryCache{}
+	_ Cache = &DiskCache{}
+)
@@ -0,0 +1,147 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	newDag := func(cfg CacheConfig[int, int]) (*Dag[int, int], *atomic.Int32) {
+		var calls atomic.Int32
+		d := New[int, int]().SetGraph(newDiamondGraph())
+		d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+			return func(ctx context.Context, s *State[int, int]) (int, error) {
+				if nodeName == "b" {
+					calls.Add(1)
+					if s.Input < 0 {
+						return 0, errors.New("negative")
+					}
+				}
+				return s.Input + len(s.DependNodeResult), nil
+			}
+		})
+		d.SetCache("b", cfg)
+		return d, &calls
+	}
+	t.Run("memory", func(t *testing.T) {
+		d, calls := newDag(CacheConfig[int, int]{Cache: NewMemoryCache(10)})
+		want := []CacheStatus{CacheMiss, CacheHit, CacheMiss}
+		for i, input := range []int{1, 1, 2} {
+			res := d.RunAsyncResult(context.TODO(), input)
+			if res.Err != nil || res.Nodes["b"].Cache != want[i] || res.Nodes["a"].Cache != CacheNone {
+				t.Fatal(i, res.Err, res.Nodes["b"].Cache)
+			}
+		}
+		if calls.Load() != 2 {
+			t.Fatal(calls.Load())
+		}
+		// 错误不缓存
+		for i := 0; i < 2; i++ {
+			if res := d.RunSyncResult(context.TODO(), -1); res.Err == nil || res.Nodes["b"].Cache != CacheMiss {
+				t.Fatal(res.Err)
+			}
+		}
+	})
+	t.Run("ttl and key", func(t *testing.T) {
+		d, calls := newDag(CacheConfig[int, int]{
+			Cache: NewMemoryCache(10),
+			TTL:   time.Millisecond,
+			Key: func(s *State[int, int]) (string, error) {
+				return "same", nil
+			},
+		})
+		d.RunSync(context.TODO(), 1)
+		if v, _ := d.RunSync(context.TODO(), 2); v != 4 || calls.Load() != 1 {
+			t.Fatal("custom key should hit", v, calls.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+		d.RunSync(context.TODO(), 2)
+		if calls.Load() != 2 {
+			t.Fatal("expired entry should miss")
+		}
+	})
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		c, err := NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, calls := newDag(CacheConfig[int, int]{Cache: c})
+		d.RunSync(context.TODO(), 1)
+		// 新的实例读取同一个目录
+		c2, _ := NewDiskCache(dir)
+		d2, calls2 := newDag(CacheConfig[int, int]{Cache: c2})
+		if res := d2.RunSyncResult(context.TODO(), 1); res.Nodes["b"].Cache != CacheHit || calls.Load()+calls2.Load() != 1 {
+			t.Fatal(res.Nodes["b"].Cache)
+		}
+		c.Set("k", []byte("v"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		if _, ok := c.Get("k"); ok {
+			t.Fatal("expired")
+		}
+	})
+}
+
+func TestMemoryCache_LRU(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	c.Get("a")
+	c.Set("c", []byte("3"), 0)
+	if _, ok := c.Get("b"); ok || c.Len() != 2 {
+		t.Fatal("b should be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Fatal(v)
+	}
+}
+
+func TestDefaultCacheKey_Lossy(t *testing.T) {
+	type opaque struct{ id int }
+	type inner struct{ Name string }
+	type tagged struct {
+		A string
+		B string `json:"-"`
+	}
+	type ok struct {
+		inner
+		At   time.Time
+		Data []byte
+		Any  any
+	}
+	key := func(in any) error {
+		_, err := DefaultCacheKey(&State[any, int]{Input: in})
+		return err
+	}
+	for _, in := range []any{opaque{1}, tagged{"a", "b"}, []any{func() {}}, ok{Any: &opaque{}}} {
+		if err := key(in); !errors.Is(err, ErrCacheKeyLossy) {
+			t.Fatalf("%T: %v", in, err)
+		}
+	}
+	if err := key(ok{inner{"x"}, time.Now(), []byte("d"), map[string]int{"a": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	// 不同的输入不会因为编码相同而命中缓存
+	var calls atomic.Int32
+	d := New[opaque, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[opaque, int] {
+		return func(ctx context.Context, s *State[opaque, int]) (int, error) {
+			if nodeName == "b" {
+				calls.Add(1)
+			}
+			return s.Input.id, nil
+		}
+	})
+	d.SetCache("b", CacheConfig[opaque, int]{Cache: NewMemoryCache(10)})
+	for _, id := range []int{1, 2} {
+		if res := d.RunSyncResult(context.TODO(), opaque{id}); res.Err != nil || res.Nodes["b"].Cache != CacheNone {
+			t.Fatal(id, res.Err, res.Nodes["b"].Cache)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatal(calls.Load())
+	}
+}
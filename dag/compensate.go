@@ -0,0 +1,92 @@
+package dag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CompensateFunc 运行失败时撤销节点的副作用, s 为节点执行时的 State, output 为节点当时的输出
+type CompensateFunc[K, V any] func(ctx context.Context, s *State[K, V], output V) error
+
+// SetCompensation 为节点注册补偿函数. 运行失败时, 本次运行中实际执行成功的节点按完成顺序(Order)倒序执行补偿,
+// 补偿不受运行 ctx 取消的影响, 某个补偿失败不影响其余补偿
+func (r *Dag[K, V]) SetCompensation(nodeName string, fn CompensateFunc[K, V]) {
+	compensations := make(map[string]CompensateFunc[K, V])
+	w, _ := r.compensations.Load().(map[string]CompensateFunc[K, V])
+	for k, v := range w {
+		compensations[k] = v
+	}
+	compensations[nodeName] = fn
+	r.compensations.Store(compensations)
+}
+
+func (r *Dag[K, V]) getCompensations() map[string]CompensateFunc[K, V] {
+	w, _ := r.compensations.Load().(map[string]CompensateFunc[K, V])
+	return w
+}
+
+// run 执行一次, 失败时执行补偿
+func (r *Dag[K, V]) run(ctx context.Context, w *ExecuteState[K, V], k K, async bool) (V, error) {
+	var (
+		v   V
+		err error
+	)
+	if async {
+		v, err = w.RunAsync(ctx, k)
+	} else {
+		v, err = w.RunSync(ctx, k)
+	}
+	if err != nil {
+		w.compensate(ctx)
+	}
+	return v, err
+}
+
+func (r *ExecuteState[K, V]) compensate(ctx context.Context) {
+	if len(r.compensations) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	var done []*NodeOutput[V]
+	r.read(func() {
+		for _, v := range r.NodeResult {
+			out, _ := v.(*NodeOutput[V])
+			// 缓存命中, 共享其他运行的执行和降级的结果没有在本次运行中产生副作用
+			if out == nil || !out.Valid || out.Err != nil || out.Cache == CacheHit || out.Shared || out.Fallback {
+				continue
+			}
+			if r.compensations[out.Node.Name] != nil {
+				done = append(done, out)
+			}
+		}
+	})
+	sort.Slice(done, func(i, j int) bool {
+		return done[i].Order > done[j].Order
+	})
+	for _, out := range done {
+		fn := r.compensations[out.Node.Name]
+		start := time.Now()
+		err := func() (err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					err = fmt.Errorf("panic error: %v", v)
+				}
+			}()
+			s, _ := out.state.(*State[K, V])
+			return fn(ctx, s, out.V)
+		}()
+		r.write(func() {
+			out.Compensation = &CompensationReport{Start: start, Duration: time.Since(start), Err: err}
+			r.compensated = append(r.compensated, out.Node.Name)
+		})
+	}
+}
+
+// CompensationReport 一个节点补偿的执行结果
+type CompensationReport struct {
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
@@ -0,0 +1,178 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func TestCompensation(t *testing.T) {
+	errShip := errors.New("ship failed")
+	errRefund := errors.New("refund failed")
+	newOrder := func(fail bool) (*Dag[string, string], *[]string) {
+		d := New[string, string]().SetGraph(graph.NewGraph(
+			graph.WithNodes(
+				&graph.Node{Name: "reserve"},
+				&graph.Node{Name: "charge"},
+				&graph.Node{Name: "notify"},
+				&graph.Node{Name: "ship"},
+			),
+			graph.WithDependOn("charge", "reserve"),
+			graph.WithDependOn("notify", "reserve"),
+			graph.WithDependOn("ship", "charge", "notify"),
+		))
+		d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[string, string] {
+			return func(ctx context.Context, s *State[string, string]) (string, error) {
+				if nodeName == "ship" && fail {
+					return "", errShip
+				}
+				return nodeName + ":" + s.Input, nil
+			}
+		})
+		var (
+			protect sync.Mutex
+			undone  []string
+		)
+		undo := func(err error) CompensateFunc[string, string] {
+			return func(ctx context.Context, s *State[string, string], output string) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				protect.Lock()
+				defer protect.Unlock()
+				undone = append(undone, output)
+				return err
+			}
+		}
+		d.SetCompensation("reserve", undo(nil))
+		d.SetCompensation("charge", undo(errRefund))
+		d.SetCompensation("ship", undo(nil))
+		return d, &undone
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		for _, async := range []bool{true, false} {
+			d, undone := newOrder(true)
+			var res *RunResult[string]
+			if async {
+				res = d.RunAsyncResult(context.TODO(), "o1")
+			} else {
+				res = d.RunSyncResult(context.TODO(), "o1")
+			}
+			if !errors.Is(res.Err, errShip) {
+				t.Fatal(res.Err)
+			}
+			// 逆序补偿, 失败的节点和没有补偿函数的节点不参与
+			if !slices.Equal(*undone, []string{"charge:o1", "reserve:o1"}) || !slices.Equal(res.Compensated, []string{"charge", "reserve"}) {
+				t.Fatal(*undone, res.Compensated)
+			}
+			if res.Nodes["reserve"].Order > res.Nodes["charge"].Order {
+				t.Fatal("order")
+			}
+			if c := res.Nodes["charge"].Compensation; c == nil || !errors.Is(c.Err, errRefund) {
+				t.Fatal(c)
+			}
+			if res.Nodes["reserve"].Compensation.Err != nil || res.Nodes["ship"].Compensation != nil || res.Nodes["notify"].Compensation != nil {
+				t.Fatal("unexpected compensation")
+			}
+		}
+	})
+	t.Run("succeeded", func(t *testing.T) {
+		d, undone := newOrder(false)
+		if res := d.RunAsyncResult(context.TODO(), "o2"); res.Err != nil || len(*undone) != 0 || len(res.Compensated) != 0 {
+			t.Fatal(res.Err, *undone)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		d, undone := newOrder(false)
+		d.SetSignal("ship", SignalConfig{})
+		h := d.Submit(context.TODO(), "o3")
+		waitStatus(t, h, func(st RunStatus) bool { return len(st.Waiting) == 1 })
+		h.Cancel(nil)
+		res := h.Wait()
+		if !errors.Is(res.Err, context.Canceled) || len(*undone) != 2 {
+			t.Fatal(res.Err, *undone)
+		}
+	})
+}
+
+func TestCompensation_NotExecuted(t *testing.T) {
+	var charges atomic.Int32
+	release := make(chan struct{})
+	d := New[string, string]().SetGraph(graph.NewGraph(
+		graph.WithNodes(
+			&graph.Node{Name: "reserve"},
+			&graph.Node{Name: "charge"},
+			&graph.Node{Name: "ship"},
+		),
+		graph.WithDependOn("charge", "reserve"),
+		graph.WithDependOn("ship", "charge"),
+	))
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[string, string] {
+		return func(ctx context.Context, s *State[string, string]) (string, error) {
+			switch nodeName {
+			case "charge":
+				charges.Add(1)
+				if s.Input != "warm" {
+					<-release
+				}
+			case "ship":
+				if s.Input == "fail" {
+					return "", errors.New("ship failed")
+				}
+			}
+			return nodeName, nil
+		}
+	})
+	same := func(s *State[string, string]) (string, error) {
+		return "same", nil
+	}
+	d.SetCache("reserve", CacheConfig[string, string]{Cache: NewMemoryCache(10), Key: same})
+	d.SetNodeSingleflight("charge", same)
+	var (
+		protect sync.Mutex
+		undone  []string
+	)
+	for _, name := range []string{"reserve", "charge"} {
+		d.SetCompensation(name, func(ctx context.Context, s *State[string, string], output string) error {
+			protect.Lock()
+			defer protect.Unlock()
+			undone = append(undone, output)
+			return nil
+		})
+	}
+	if _, err := d.RunSync(context.TODO(), "warm"); err != nil {
+		t.Fatal(err)
+	}
+	leader := make(chan error)
+	go func() {
+		_, err := d.RunAsync(context.TODO(), "ok")
+		leader <- err
+	}()
+	for charges.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	follower := make(chan *RunResult[string])
+	go func() {
+		follower <- d.RunAsyncResult(context.TODO(), "fail")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-leader; err != nil {
+		t.Fatal(err)
+	}
+	// 缓存命中和共享的节点没有在失败的运行中执行, 不补偿
+	res := <-follower
+	if res.Err == nil || res.Nodes["reserve"].Cache != CacheHit || !res.Nodes["charge"].Shared {
+		t.Fatal(res.Err, res.Nodes["reserve"].Cache, res.Nodes["charge"].Shared)
+	}
+	if len(undone) != 0 || len(res.Compensated) != 0 {
+		t.Fatal(undone, res.Compensated)
+	}
+}
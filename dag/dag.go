@@ -9,12 +9,36 @@ import (
 	"maps"
 
 	"github.com/opengeektech/go-dag/graph"
+	"golang.org/x/sync/singleflight"
 )
 
 type Dag[K, V any] struct {
 	name    string
-	graph   *graph.Graph
+	graph   atomic.Pointer[graph.Graph]
 	funcMap atomic.Value
+	// explicit 通过 SetFunc 和 WithNodeFunc 设置的处理函数, 重新绑定 registry 时保留
+	explicit atomic.Value
+	registry *Registry[K, V]
+	// reload 保证运行开始时取到的图和处理函数属于同一版本
+	reload sync.RWMutex
+	// runs Submit 提交且尚未结束的运行
+	runs sync.Map
+	// states 所有未结束运行的状态, 按 RunID 索引
+	states  sync.Map
+	signals atomic.Value
+	caches  atomic.Value
+	// runKey, runGroup 合并相同输入的并发运行, nodeFlights, nodeGroup 合并节点的并发执行
+	runKey        atomic.Value
+	runGroup      singleflight.Group
+	nodeFlights   atomic.Value
+	nodeGroup     singleflight.Group
+	compensations atomic.Value
+	kinds         atomic.Value
+	breakers      atomic.Value
+	// resources 只在该 Dag 的运行之间共享的资源, nodeResources 节点需要占用的资源
+	resources     ResourcePool
+	nodeResources atomic.Value
+	rateLimits    atomic.Value
 	// valid   bool
 }
 type Option[K, V any] func(d *Dag[K, V])
@@ -37,6 +61,7 @@ func WithNodeFunc[K, V any](funcMapIn map[string]HandlerFunc[K, V]) Option[K, V]
 			funcMap[k] = v
 		}
 		d.funcMap.Store(funcMap)
+		d.setExplicit(funcMapIn)
 	}
 }
 func (d *Dag[K, V]) getFuncMap() map[string]HandlerFunc[K, V] {
@@ -52,10 +77,15 @@ func (d *Dag[K, V]) newFuncMap() map[string]HandlerFunc[K, V] {
 	return funcMap
 }
 func (r *Dag[K, V]) SetGraph(g *graph.Graph) *Dag[K, V] {
-	r.graph = g
+	r.graph.Store(g)
 	r.name = g.GraphName
 	return r
 }
+
+// Graph 返回当前使用的图, 热加载后会变化
+func (r *Dag[K, V]) Graph() *graph.Graph {
+	return r.graph.Load()
+}
 func (r *Dag[K, V]) SetName(n string) *Dag[K, V] {
 	r.name = n
 	return r
@@ -64,7 +94,11 @@ func (r *Dag[K, V]) Name() string {
 	return r.name
 }
 func New[K, V any](opts ...Option[K, V]) *Dag[K, V] {
-	return &Dag[K, V]{}
+	d := &Dag[K, V]{}
+	for _, fn := range opts {
+		fn(d)
+	}
+	return d
 }
 
 func (r *Dag[K, V]) HasFunc(nodeName string) bool {
@@ -76,7 +110,7 @@ func (r *Dag[K, V]) HasFunc(nodeName string) bool {
 	return ok
 }
 func (r *Dag[K, V]) Wrapfunc(fun func(nodeName string, id uint32, fn HandlerFunc[K, V]) HandlerFunc[K, V]) {
-	for _, node := range r.graph.NodeIdMapping {
+	for _, node := range r.graph.Load().NodeIdMapping {
 		h := r.getFuncMap()
 
 		if h != nil {
@@ -90,7 +124,7 @@ func (r *Dag[K, V]) Wrapfunc(fun func(nodeName string, id uint32, fn HandlerFunc
 	}
 }
 func (r *Dag[K, V]) RegisterFunc(fn func(nodeName string, id uint32) HandlerFunc[K, V]) {
-	for _, node := range r.graph.NodeIdMapping {
+	for _, node := range r.graph.Load().NodeIdMapping {
 		h := r.getFuncMap()
 		if h == nil {
 			r.SetFunc(node.Name, fn(node.Name, node.Id))
@@ -107,84 +141,99 @@ func (r *Dag[K, V]) SetFunc(nodeName string, fun HandlerFunc[K, V]) {
 	}
 	funcMap[nodeName] = newFunc
 	r.funcMap.Store(funcMap)
+	r.setExplicit(map[string]HandlerFunc[K, V]{nodeName: newFunc})
 }
 
 func NewGraphDag[K any, V any](g *graph.Graph) *Dag[K, V] {
-	return &Dag[K, V]{
-		name:  g.GraphName,
-		graph: g,
-	}
+	d := &Dag[K, V]{name: g.GraphName}
+	d.graph.Store(g)
+	return d
 }
-func (r *Dag[K, V]) RunAsync(ctx context.Context, k K) (V, error) {
+func (r *Dag[K, V]) newExecuteState() *ExecuteState[K, V] {
+	r.reload.RLock()
+	defer r.reload.RUnlock()
 	w := &ExecuteState[K, V]{
-		Funcs: make(map[string]HandlerFunc[K, V]),
-		G:     r.graph,
+		Funcs:   make(map[string]HandlerFunc[K, V]),
+		G:       r.graph.Load(),
+		RunID:   newRunID(),
+		signals: r.newSignalBoxes(),
+		caches:  r.getCaches(),
+		flights: r.getNodeFlights(),
+		group:   &r.nodeGroup,
+
+		compensations: r.getCompensations(),
+		kinds:         r.getKinds(),
+		breakers:      r.getBreakers(),
+		resources:     &r.resources,
+		nodeResources: r.getNodeResources(),
+		rateLimits:    r.getRateLimits(),
 	}
+	w.watches = failureWatches(w.G, w.kinds)
 	t := r.newFuncMap()
 	maps.Copy(w.Funcs, t)
-	defer func() {
-		if w.GraphIter != nil {
-			w.GraphIter.Reset()
-			graph.GraphStatePool.Put(w.GraphIter)
-		}
-	}()
-	v, err := w.RunAsync(ctx, k)
-	return v, err
+	r.states.Store(w.RunID, w)
+	return w
 }
-func (r *Dag[K, V]) RunSync(ctx context.Context, k K) (V, error) {
-	w := &ExecuteState[K, V]{
-		Funcs: make(map[string]HandlerFunc[K, V]),
-		G:     r.graph,
+func (r *Dag[K, V]) release(w *ExecuteState[K, V]) {
+	r.states.Delete(w.RunID)
+	if w.GraphIter != nil {
+		w.GraphIter.Reset()
+		graph.GraphStatePool.Put(w.GraphIter)
+		w.GraphIter = nil
 	}
-	t := r.newFuncMap()
-	maps.Copy(w.Funcs, t)
-	defer func() {
-		if w.GraphIter != nil {
-			w.GraphIter.Reset()
-			graph.GraphStatePool.Put(w.GraphIter)
-		}
-	}()
-	v, err := w.RunSync(ctx, k)
-	return v, err
+}
+func (r *Dag[K, V]) RunAsync(ctx context.Context, k K) (V, error) {
+	return r.coalesce(ctx, k, func(ctx context.Context) (V, error) {
+		w := r.newExecuteState()
+		defer r.release(w)
+		return r.run(ctx, w, k, true)
+	})
+}
+func (r *Dag[K, V]) RunSync(ctx context.Context, k K) (V, error) {
+	return r.coalesce(ctx, k, func(ctx context.Context) (V, error) {
+		w := r.newExecuteState()
+		defer r.release(w)
+		return r.run(ctx, w, k, false)
+	})
 }
 
-var (
-	dagMap sync.Map
-)
+// RunAsyncResult 与 RunAsync 相同, 额外返回每个节点的执行情况
+func (r *Dag[K, V]) RunAsyncResult(ctx context.Context, k K) *RunResult[V] {
+	w := r.newExecuteState()
+	defer r.release(w)
+	v, err := r.run(ctx, w, k, true)
+	return w.result(v, err)
+}
+func (r *Dag[K, V]) RunSyncResult(ctx context.Context, k K) *RunResult[V] {
+	w := r.newExecuteState()
+	defer r.release(w)
+	v, err := r.run(ctx, w, k, false)
+	return w.result(v, err)
+}
 
 func SetGlobalDag[K, V any](Name string, d *Dag[K, V]) {
-	if d == nil {
-		dagMap.Delete(Name)
-		return
-	}
-	dagMap.Store(Name, d)
+	Register(DefaultNamespace, Name, d)
 }
+
+// GetGlobalDag 不存在或类型参数不一致时返回 nil, 需要区分原因时使用 LookupGlobalDag
 func GetGlobalDag[K, V any](Name string) *Dag[K, V] {
-	f, ok := dagMap.Load(Name)
-	if !ok {
-		return nil
-	}
-	h, _ := f.(*Dag[K, V])
-	return h
+	d, _ := Lookup[K, V](DefaultNamespace, Name)
+	return d
+}
+func LookupGlobalDag[K, V any](Name string) (*Dag[K, V], error) {
+	return Lookup[K, V](DefaultNamespace, Name)
 }
+
 var (
 	ErrDagNotFound = fmt.Errorf("Err dag Not found")
+	// ErrNodesNotReady 运行结束时仍有节点的依赖没有完成
+	ErrNodesNotReady = fmt.Errorf("nodes never became ready")
 )
 
 func Run[K, V any](Name string, ctx context.Context, params K) (V, error) {
-	dg := GetGlobalDag[K, V](Name)
-	if dg == nil || dg.graph == nil {
-		var e V
-		return e, ErrDagNotFound
-	}
-	return dg.RunSync(ctx, params)
+	return RunIn[K, V](DefaultNamespace, Name, ctx, params)
 }
 
 func RunAsync[K, V any](Name string, ctx context.Context, params K) (V, error) {
-	dg := GetGlobalDag[K, V](Name)
-	if dg == nil || dg.graph == nil {
-		var e V
-		return e, ErrDagNotFound
-	}
-	return dg.RunAsync(ctx, params)
+	return RunAsyncIn[K, V](DefaultNamespace, Name, ctx, params)
 }
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"github.com/opengeektech/go-dag/graph"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type ExecuteState[K, V any] struct {
@@ -20,6 +22,32 @@ type ExecuteState[K, V any] struct {
 	Active     chan uint32
 	Recv       chan uint32
 	OrdIdAlloc uint32
+	// running 已开始但未结束的节点及开始时间
+	running map[uint32]time.Time
+	gate    *pauseGate
+	// RunID 由执行器分配, 用于 Dag.Signal 等按运行寻址的接口
+	RunID   string
+	signals map[string]*signalBox
+	caches  map[string]CacheConfig[K, V]
+	flights map[string]func(s *State[K, V]) (string, error)
+	group   *singleflight.Group
+
+	compensations map[string]CompensateFunc[K, V]
+	// compensated 已执行补偿的节点, 按执行顺序
+	compensated []string
+
+	kinds         map[string]nodeKind
+	watches       map[uint32][]uint32
+	breakers      map[string]*nodeBreaker[K, V]
+	resources     *ResourcePool
+	nodeResources map[string][]ResourceNeed
+	rateLimits    map[string]*tokenBucket
+	// failure 运行中第一个错误, 之后只派发清理和错误处理节点
+	failure error
+	// abort 运行失败或取消时关闭, 调度器不再等待暂停
+	abort <-chan struct{}
+	// stalled 还有节点未执行但都不可能就绪时由调度器设置
+	stalled error
 }
 
 func (r *ExecuteState[K, V]) sendChan(ch chan uint32, k uint32) {
@@ -50,6 +78,11 @@ type State[K any, V any] struct {
 	CurrentNode      graph.Node
 	NodeOrders       map[string]uint32
 	DependNodeResult map[string]V
+	RunID            string
+	// Err 清理节点为运行中第一个错误, 错误处理节点为所监视上游节点的错误
+	Err error
+	// Signal 信号节点收到的数据
+	Signal any
 }
 
 type dependstack struct {
@@ -93,46 +126,58 @@ func (r *ExecuteState[K, V]) RunAsync(ctx context.Context, input K) (V, error) {
 		err error
 	)
 	defer r.iterclose()
-	ch := r.IterChan()
 	wg, ctx := errgroup.WithContext(ctx)
+	r.abort = ctx.Done()
+	ch := r.IterChan()
 	var (
 		lastValue atomic.Value
 	)
 	wg.Go(func() error {
-		for {
-			if ctx.Err() != nil {
-				return ctx.Err()
+		// 失败或取消后继续接收, 清理和错误处理节点仍需执行, 其余节点直接结束
+		for nodeId := range ch {
+			if nodeId == 0 {
+				panic("illgal NodeId ")
 			}
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case nodeId, ok := <-ch:
-				if !ok {
-					return nil
-				}
-				if nodeId == 0 {
-					panic("illgal NodeId ")
-				}
-				wg.Go(func() error {
-					gg := r.G.FindNode(nodeId)
-					t1, err := r.RunNodeBlock(ctx, gg, input)
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					if err == nil {
-						lastValue.Store(t1)
-					}
-					return err
+			gg := r.G.FindNode(nodeId)
+			failing := ctx.Err() != nil
+			if failing {
+				r.write(func() {
+					r.setFailure(context.Cause(ctx))
 				})
-
 			}
+			if !r.shouldDispatch(gg, failing) {
+				r.notReached(gg)
+				continue
+			}
+			nctx := r.nodeContext(ctx, gg)
+			wg.Go(func() error {
+				t1, err := r.RunNodeBlock(nctx, gg, input)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err == nil {
+					lastValue.Store(t1)
+				}
+				return err
+			})
 		}
+		return ctx.Err()
 	})
 	err = wg.Wait()
+	if err == nil {
+		err = r.stalledErr()
+	}
 	b, _ := lastValue.Load().(V)
 	return b, err
 }
 
+func (r *ExecuteState[K, V]) stalledErr() (err error) {
+	r.read(func() {
+		err = r.stalled
+	})
+	return err
+}
+
 func (r *ExecuteState[K, V]) RunSync(ctx context.Context, input K) (V, error) {
 	r.ensure()
 	var (
@@ -144,23 +189,48 @@ func (r *ExecuteState[K, V]) RunSync(ctx context.Context, input K) (V, error) {
 		if nodeId == 0 {
 			panic("illgal NodeId ")
 		}
-		if ctx.Err() != nil {
-			return v, ctx.Err()
+		node := r.G.FindNode(nodeId)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+			r.write(func() {
+				r.setFailure(err)
+			})
 		}
-		v, err = r.RunNodeBlock(ctx, r.G.FindNode(nodeId), input)
-		if err != nil {
-			return v, err
+		if !r.shouldDispatch(node, err != nil) {
+			r.notReached(node)
+			continue
 		}
+		v1, err1 := r.RunNodeBlock(r.nodeContext(ctx, node), node, input)
+		if err == nil {
+			v, err = v1, err1
+		}
+	}
+	if err == nil {
+		err = r.stalledErr()
 	}
 	return v, err
 }
 
 type NodeOutput[V any] struct {
-	V     V
-	Node  *graph.Node
-	Err   error
-	Order uint32
-	Valid bool
+	V        V
+	Node     *graph.Node
+	Err      error
+	Order    uint32
+	Valid    bool
+	Start    time.Time
+	Duration time.Duration
+	Cache    CacheStatus
+	// Shared 节点结果来自其他运行中同时进行的相同执行
+	Shared bool
+	// Breaker 执行时熔断器的状态, Fallback 表示被熔断后执行了降级函数
+	Breaker  BreakerState
+	Fallback bool
+	// QueueTime 执行前等待限流和资源的时间, 包含在 Duration 中
+	QueueTime time.Duration
+	// Compensation 运行失败后执行的补偿, 未执行时为 nil
+	Compensation *CompensationReport
+	// state 节点执行时的 *State[K, V], 供补偿使用
+	state any
 }
 
 func (r *ExecuteState[K, V]) iterDone(id uint32) {
@@ -186,10 +256,19 @@ func (state *ExecuteState[K, V]) RunNodeBlock(ctx context.Context, node *graph.N
 	var ordId = uint32(0)
 	var resultMap = make(map[string]V)
 	var ordMap = make(map[string]uint32)
+	var (
+		specialErr error
+		skip       bool
+	)
 	state.read(func() {
+		specialErr, skip = state.specialError(node)
 		for _, depId := range depend {
 			ord, ok := state.NodeOrder[depId]
 			if !ok {
+				// 运行失败后未执行的依赖
+				if state.failure != nil {
+					continue
+				}
 				panic(fmt.Sprintf("node %d not found", depId))
 			}
 			dependResult, _ := state.NodeResult[depId].(*NodeOutput[V])
@@ -209,9 +288,43 @@ func (state *ExecuteState[K, V]) RunNodeBlock(ctx context.Context, node *graph.N
 		NodeOrders:       ordMap,
 		CurrentNode:      *node,
 		Last:             lastNodeOutput,
+		RunID:            state.RunID,
+		Err:              specialErr,
 	}
 	handler, ok := state.Funcs[node.Name]
-	if !ok || handler == nil {
+	var (
+		cacheStatus  CacheStatus
+		shared       bool
+		breakerState BreakerState
+		fallback     bool
+		queueTime    time.Duration
+	)
+	// 熔断器只记录处理函数本身的结果, 但在等待限流和资源之前检查, 打开时直接失败
+	var call *breakerCall[K, V]
+	if nb := state.breakers[node.Name]; nb != nil && handler != nil {
+		call = &breakerCall[K, V]{nb: nb, state: &breakerState, fallback: &fallback}
+		handler = call.record(handler)
+	}
+	if needs := state.nodeResources[node.Name]; len(needs) > 0 && handler != nil {
+		handler = resourceHandler(state.resources, needs, handler, &queueTime)
+	}
+	// 先等待令牌再占用资源, 避免限流等待期间占着资源
+	if b := state.rateLimits[node.Name]; b != nil && handler != nil {
+		handler = rateLimitHandler(b, handler, &queueTime)
+	}
+	if call != nil {
+		handler = call.gate(handler)
+	}
+	if keyFn := state.flights[node.Name]; keyFn != nil && handler != nil && state.group != nil {
+		handler = flightHandler(state.group, keyFn, handler, &shared)
+	}
+	if cfg, has := state.caches[node.Name]; has && handler != nil && cfg.Cache != nil {
+		handler = cachedHandler(cfg, handler, &cacheStatus, &fallback)
+	}
+	if box := state.signals[node.Name]; box != nil {
+		handler, ok = signalHandler(box, handler), true
+	}
+	if skip || !ok || handler == nil {
 		state.write(func() {
 			state.OrdIdAlloc++
 			state.NodeOrder[node.Id] = state.OrdIdAlloc
@@ -230,6 +343,13 @@ func (state *ExecuteState[K, V]) RunNodeBlock(ctx context.Context, node *graph.N
 		output V
 		err error
 	)
+	start := time.Now()
+	state.write(func() {
+		if state.running == nil {
+			state.running = make(map[uint32]time.Time)
+		}
+		state.running[node.Id] = start
+	})
 	output,err = func () (output V, err error) {
 		defer func () {
 			err1 := recover()
@@ -240,14 +360,24 @@ func (state *ExecuteState[K, V]) RunNodeBlock(ctx context.Context, node *graph.N
 		return handler(ctx, st)
 	}()
 	state.write(func() {
+		state.setFailure(err)
+		delete(state.running, node.Id)
 		state.OrdIdAlloc++
 		state.NodeOrder[node.Id] = state.OrdIdAlloc
 		state.NodeResult[node.Id] = &NodeOutput[V]{
-			V:     output,
-			Node:  node,
-			Err:   err,
-			Order: state.OrdIdAlloc,
-			Valid: true,
+			V:         output,
+			Node:      node,
+			Err:       err,
+			Order:     state.OrdIdAlloc,
+			Valid:     true,
+			Start:     start,
+			Duration:  time.Since(start),
+			Cache:     cacheStatus,
+			Shared:    shared,
+			Breaker:   breakerState,
+			Fallback:  fallback,
+			QueueTime: queueTime,
+			state:     st,
 		}
 		state.sendChan(state.Recv, node.Id)
 	})
@@ -280,7 +410,6 @@ func (r *ExecuteState[K, V]) doPush(activeNodeId chan uint32, recv chan uint32)
 	var h nodeHelper
 	h.g = r.G
 	h.Init()
-Outer:
 	for r.GraphIter.HasNext() {
 		pending, err := r.GraphIter.Next()
 		if err != nil {
@@ -289,12 +418,29 @@ Outer:
 		for _, v := range pending {
 			h.PushPending(v.Id)
 		}
+	}
+	// 所有节点都完成后才结束, 依赖未满足的节点等待前驱完成后再派发
+	abort, aborted := r.abort, false
+Outer:
+	for h.Remaining() > 0 {
 		next := h.CheckPrepare()
 		cursor := 0
 		for cursor < len(next) {
+			// 暂停时不再派发, 但继续接收执行完成的节点
+			send := activeNodeId
+			var wake <-chan struct{}
+			if !aborted {
+				wake = r.gate.wait(r.G.FindNode(next[cursor]).Name)
+			}
+			if wake != nil {
+				send = nil
+			}
 			select {
-			case activeNodeId <- next[cursor]:
+			case send <- next[cursor]:
 				cursor++
+			case <-wake:
+			case <-abort:
+				aborted, abort = true, nil
 			case val, active := <-recv:
 				if active {
 					h.SetDone(val, val)
@@ -303,6 +449,16 @@ Outer:
 				}
 			}
 		}
+		if len(h.processing) == 0 {
+			if len(next) == 0 {
+				// 没有正在执行的节点, 剩余节点的依赖不会再完成
+				r.write(func() {
+					r.stalled = fmt.Errorf("%w: %d nodes", ErrNodesNotReady, len(h.pending))
+				})
+				break Outer
+			}
+			continue
+		}
 		val, active := <-recv
 		if active {
 			h.SetDone(val, val)
@@ -3,6 +3,7 @@ package dag
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -321,3 +322,94 @@ func Test_iter2(t *testing.T) {
 	})
 
 }
+
+func Test_laterWave(t *testing.T) {
+	// d 依赖的 b 比 c 晚完成, d 要等到 b 完成后的下一轮才能派发
+	gg := graph.NewGraph(
+		graph.WithNodes(
+			&graph.Node{Name: "a"},
+			&graph.Node{Name: "b"},
+			&graph.Node{Name: "c"},
+			&graph.Node{Name: "d"},
+		),
+		graph.WithDependOn("b", "a"),
+		graph.WithDependOn("c", "a"),
+		graph.WithDependOn("d", "b", "c"),
+	)
+	newState := func() *ExecuteState[int, int] {
+		return &ExecuteState[int, int]{
+			Funcs: map[string]HandlerFunc[int, int]{
+				"a": func(ctx context.Context, s *State[int, int]) (int, error) {
+					return s.Input + 1, nil
+				},
+				"b": func(ctx context.Context, s *State[int, int]) (int, error) {
+					time.Sleep(20 * time.Millisecond)
+					return s.DependNodeResult["a"] * 2, nil
+				},
+				"c": func(ctx context.Context, s *State[int, int]) (int, error) {
+					return s.DependNodeResult["a"] * 3, nil
+				},
+				"d": func(ctx context.Context, s *State[int, int]) (int, error) {
+					return s.DependNodeResult["b"] + s.DependNodeResult["c"], nil
+				},
+			},
+			G: gg,
+		}
+	}
+	for _, async := range []bool{true, false} {
+		g := newState()
+		var (
+			v   int
+			err error
+		)
+		if async {
+			v, err = g.RunAsync(context.TODO(), 1)
+		} else {
+			v, err = g.RunSync(context.TODO(), 1)
+		}
+		// (1+1)*2 + (1+1)*3
+		if err != nil || v != 10 {
+			t.Fatal(async, v, err)
+		}
+	}
+}
+
+func Test_stalled(t *testing.T) {
+	// b 依赖一个不存在的节点, 永远不会就绪, 调度器不能空转
+	gg := graph.NewGraph(
+		graph.WithNodes(&graph.Node{Name: "a"}, &graph.Node{Name: "b"}),
+		graph.WithDependOn("b", "a"),
+	)
+	gg.DependOnMap[gg.FindNodeByName("b").Id][99] = struct{}{}
+	for _, async := range []bool{true, false} {
+		g := &ExecuteState[int, int]{
+			Funcs: map[string]HandlerFunc[int, int]{
+				"a": func(ctx context.Context, s *State[int, int]) (int, error) {
+					return s.Input + 1, nil
+				},
+				"b": func(ctx context.Context, s *State[int, int]) (int, error) {
+					return s.DependNodeResult["a"] * 2, nil
+				},
+			},
+			G: gg,
+		}
+		done := make(chan error)
+		go func() {
+			var err error
+			if async {
+				_, err = g.RunAsync(context.TODO(), 1)
+			} else {
+				_, err = g.RunSync(context.TODO(), 1)
+			}
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrNodesNotReady) {
+				t.Fatal(async, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal(async, "scheduler did not stop")
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Package event 进程内事件总线, 事件到达时触发 dag 运行
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opengeektech/go-dag/dag"
+	"github.com/opengeektech/go-dag/dag/schedule"
+)
+
+var (
+	ErrBusClosed = errors.New("event bus closed")
+)
+
+type Event struct {
+	Topic   string
+	Payload any
+	Time    time.Time
+}
+
+// Handler 处理一次触发, 开启去抖或批量时 events 包含合并的多个事件
+type Handler func(ctx context.Context, events []Event) error
+
+type Subscription struct {
+	// Name 用于错误报告, 默认为 Topic
+	Name string
+	// Topic 精确匹配, 以 * 结尾时按前缀匹配, "*" 匹配所有主题
+	Topic  string
+	Filter func(Event) bool
+	// Debounce 最后一个事件之后安静这么久才触发, 期间的事件合并为一次
+	Debounce time.Duration
+	// BatchSize, BatchWait 累积到 BatchSize 个事件, 或第一个事件之后经过 BatchWait 时触发
+	BatchSize int
+	BatchWait time.Duration
+	Handler   Handler
+}
+
+type subscription struct {
+	Subscription
+	bus     *Bus
+	protect sync.Mutex
+	pending []Event
+	// gen 每次取走事件后加一, 使已经触发的旧定时器失效
+	gen          int
+	stopDebounce func()
+	stopBatch    func()
+}
+
+type Option func(*Bus)
+
+// WithClock 替换去抖和批量使用的时间
+func WithClock(c schedule.Clock) Option {
+	return func(b *Bus) {
+		b.clock = c
+	}
+}
+
+func WithContext(ctx context.Context) Option {
+	return func(b *Bus) {
+		b.ctx = ctx
+	}
+}
+
+func WithErrorHandler(fn func(sub string, events []Event, err error)) Option {
+	return func(b *Bus) {
+		b.onError = fn
+	}
+}
+
+type Bus struct {
+	clock   schedule.Clock
+	ctx     context.Context
+	onError func(sub string, events []Event, err error)
+
+	protect sync.RWMutex
+	subs    []*subscription
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func NewBus(opts ...Option) *Bus {
+	b := &Bus{clock: schedule.RealClock, ctx: context.Background()}
+	for _, fn := range opts {
+		fn(b)
+	}
+	return b
+}
+
+// Subscribe 订阅主题, 返回的函数取消订阅并立即处理尚未触发的事件
+func (b *Bus) Subscribe(s Subscription) (func(), error) {
+	if s.Topic == "" || s.Handler == nil {
+		return nil, fmt.Errorf("subscription %q: topic and handler are required", s.Name)
+	}
+	if s.Name == "" {
+		s.Name = s.Topic
+	}
+	sub := &subscription{Subscription: s, bus: b}
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	b.subs = append(b.subs, sub)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.protect.Lock()
+			for i, v := range b.subs {
+				if v == sub {
+					b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+					break
+				}
+			}
+			b.protect.Unlock()
+			sub.flush(-1, -1)
+		})
+	}, nil
+}
+
+// Publish 把事件交给所有匹配的订阅, 不等待处理完成
+func (b *Bus) Publish(topic string, payload any) error {
+	e := Event{Topic: topic, Payload: payload, Time: b.clock.Now()}
+	b.protect.RLock()
+	defer b.protect.RUnlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	for _, s := range b.subs {
+		if !matchTopic(s.Topic, topic) || (s.Filter != nil && !s.Filter(e)) {
+			continue
+		}
+		s.add(e)
+	}
+	return nil
+}
+
+// Close 停止接收事件, 处理所有尚未触发的事件并等待处理结束
+func (b *Bus) Close() {
+	b.protect.Lock()
+	if b.closed {
+		b.protect.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.protect.Unlock()
+	for _, s := range subs {
+		s.flush(-1, -1)
+	}
+	b.wg.Wait()
+}
+
+func matchTopic(pattern, topic string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(topic, prefix)
+	}
+	return pattern == topic
+}
+
+func (s *subscription) add(e Event) {
+	if s.Debounce <= 0 && s.BatchSize <= 0 && s.BatchWait <= 0 {
+		s.bus.dispatch(s, []Event{e})
+		return
+	}
+	s.protect.Lock()
+	s.pending = append(s.pending, e)
+	if s.BatchSize > 0 && len(s.pending) >= s.BatchSize {
+		events := s.take()
+		s.protect.Unlock()
+		s.bus.dispatch(s, events)
+		return
+	}
+	gen := s.gen
+	if s.Debounce > 0 {
+		// 新事件重新开始计时
+		if s.stopDebounce != nil {
+			s.stopDebounce()
+		}
+		n := len(s.pending)
+		s.stopDebounce = s.bus.after(s.Debounce, func() { s.flush(gen, n) })
+	}
+	if s.BatchWait > 0 && len(s.pending) == 1 {
+		s.stopBatch = s.bus.after(s.BatchWait, func() { s.flush(gen, -1) })
+	}
+	s.protect.Unlock()
+}
+
+// flush 触发尚未处理的事件. gen 为 -1 时总是触发; n 不为 -1 时只在没有新事件到达时触发,
+// 避免已经到期但被新事件取消的去抖定时器提前触发
+func (s *subscription) flush(gen, n int) {
+	s.protect.Lock()
+	if (gen >= 0 && gen != s.gen) || (n >= 0 && n != len(s.pending)) || len(s.pending) == 0 {
+		s.protect.Unlock()
+		return
+	}
+	events := s.take()
+	s.protect.Unlock()
+	s.bus.dispatch(s, events)
+}
+
+// take 调用方持有锁
+func (s *subscription) take() []Event {
+	events := s.pending
+	s.pending = nil
+	s.gen++
+	for _, stop := range []func(){s.stopDebounce, s.stopBatch} {
+		if stop != nil {
+			stop()
+		}
+	}
+	s.stopDebounce, s.stopBatch = nil, nil
+	return events
+}
+
+func (b *Bus) dispatch(s *subscription, events []Event) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		if err := call(b.ctx, s.Handler, events); err != nil && b.onError != nil {
+			b.onError(s.Name, events, err)
+		}
+	}()
+}
+
+func call(ctx context.Context, h Handler, events []Event) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic error: %v", v)
+		}
+	}()
+	return h(ctx, events)
+}
+
+// after d 之后调用 fn, 返回的函数取消尚未触发的调用
+func (b *Bus) after(d time.Duration, fn func()) func() {
+	t := b.clock.NewTimer(d)
+	done := make(chan struct{})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		select {
+		case <-t.C():
+			fn()
+		case <-done:
+			t.Stop()
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// RunDag 每次触发时把事件转换为输入, 再从 ns 中按名字查找 dag 运行
+func RunDag[K, V any](ns *dag.Namespace, name string, mapper func(events []Event) (K, error)) Handler {
+	return func(ctx context.Context, events []Event) error {
+		d, err := dag.Lookup[K, V](ns, name)
+		if err != nil {
+			return err
+		}
+		k, err := mapper(events)
+		if err != nil {
+			return err
+		}
+		_, err = d.RunAsync(ctx, k)
+		return err
+	}
+}
+
+func RunGlobalDag[K, V any](name string, mapper func(events []Event) (K, error)) Handler {
+	return RunDag[K, V](dag.DefaultNamespace, name, mapper)
+}
+
+// Last 使用最后一个事件生成输入, 适合去抖
+func Last[K any](fn func(Event) (K, error)) func(events []Event) (K, error) {
+	return func(events []Event) (K, error) {
+		return fn(events[len(events)-1])
+	}
+}
+
+// Payloads 把所有事件的 Payload 断言为 T 组成输入, 适合批量
+func Payloads[T any](events []Event) ([]T, error) {
+	ret := make([]T, 0, len(events))
+	for _, e := range events {
+		v, ok := e.Payload.(T)
+		if !ok {
+			var want T
+			return nil, fmt.Errorf("event %s: payload is %T, not %T", e.Topic, e.Payload, want)
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
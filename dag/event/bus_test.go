@@ -0,0 +1,130 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/dag"
+	"github.com/opengeektech/go-dag/dag/schedule"
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func collect(ch chan []Event) Handler {
+	return func(ctx context.Context, events []Event) error {
+		ch <- events
+		return nil
+	}
+}
+
+func payloads(t *testing.T, events []Event) []int {
+	t.Helper()
+	v, err := Payloads[int](events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestBus(t *testing.T) {
+	t.Run("filter", func(t *testing.T) {
+		b := NewBus()
+		defer b.Close()
+		got := make(chan []Event, 10)
+		b.Subscribe(Subscription{Topic: "order.*", Filter: func(e Event) bool { return e.Payload.(int) > 10 }, Handler: collect(got)})
+		b.Publish("order.created", 5)
+		b.Publish("user.created", 50)
+		b.Publish("order.paid", 20)
+		if events := <-got; len(events) != 1 || events[0].Topic != "order.paid" {
+			t.Fatal(events)
+		}
+	})
+	t.Run("debounce", func(t *testing.T) {
+		clock := schedule.NewFakeClock(time.Now())
+		b := NewBus(WithClock(clock))
+		defer b.Close()
+		got := make(chan []Event, 10)
+		b.Subscribe(Subscription{Topic: "file", Debounce: time.Second, Handler: collect(got)})
+		for i := 1; i <= 3; i++ {
+			b.Publish("file", i)
+			clock.BlockUntil(1)
+			clock.Advance(500 * time.Millisecond)
+		}
+		select {
+		case events := <-got:
+			t.Fatal("fired early", events)
+		case <-time.After(10 * time.Millisecond):
+		}
+		clock.Advance(500 * time.Millisecond)
+		if v := payloads(t, <-got); len(v) != 3 || v[2] != 3 {
+			t.Fatal(v)
+		}
+	})
+	t.Run("batch", func(t *testing.T) {
+		clock := schedule.NewFakeClock(time.Now())
+		b := NewBus(WithClock(clock))
+		got := make(chan []Event, 10)
+		unsubscribe, _ := b.Subscribe(Subscription{Topic: "metric", BatchSize: 2, BatchWait: time.Second, Handler: collect(got)})
+		for i := 1; i <= 5; i++ {
+			b.Publish("metric", i)
+		}
+		if v := payloads(t, <-got); len(v) != 2 {
+			t.Fatal(v)
+		}
+		if v := payloads(t, <-got); len(v) != 2 {
+			t.Fatal(v)
+		}
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		if v := payloads(t, <-got); len(v) != 1 || v[0] != 5 {
+			t.Fatal(v)
+		}
+		// 取消订阅和关闭时处理剩余事件
+		b.Publish("metric", 6)
+		unsubscribe()
+		if v := payloads(t, <-got); len(v) != 1 || v[0] != 6 {
+			t.Fatal(v)
+		}
+		b.Close()
+		if err := b.Publish("metric", 7); !errors.Is(err, ErrBusClosed) {
+			t.Fatal(err)
+		}
+	})
+	t.Run("dag", func(t *testing.T) {
+		ns := dag.NewNamespace("event")
+		d := dag.New[[]int, int]().SetGraph(graph.NewGraph(graph.WithNodes(&graph.Node{Name: "sum"})))
+		d.SetFunc("sum", func(ctx context.Context, s *dag.State[[]int, int]) (int, error) {
+			sum := 0
+			for _, v := range s.Input {
+				sum += v
+			}
+			if sum > 100 {
+				return 0, errors.New("too large")
+			}
+			return sum, nil
+		})
+		dag.Register(ns, "sum", d)
+		errs := make(chan error, 10)
+		b := NewBus(WithErrorHandler(func(sub string, events []Event, err error) {
+			errs <- err
+		}))
+		b.Subscribe(Subscription{Name: "sum", Topic: "n", BatchSize: 2, Handler: RunDag[[]int, int](ns, "sum", Payloads[int])})
+		b.Subscribe(Subscription{Topic: "n", Handler: RunDag[[]int, int](ns, "missing", Payloads[int])})
+		b.Publish("n", 60)
+		b.Publish("n", 70)
+		b.Close()
+		var notFound, failed int
+		for len(errs) > 0 {
+			switch err := <-errs; {
+			case errors.Is(err, dag.ErrDagNotFound):
+				notFound++
+			case err.Error() == "too large":
+				failed++
+			}
+		}
+		if notFound != 2 || failed != 1 {
+			t.Fatal(notFound, failed)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package dag
+
+import (
+	"context"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// nodeKind 非普通节点的触发方式
+type nodeKind struct {
+	finally bool
+	// onFailure 不为 nil 时节点只在这些上游节点失败时执行, 为空表示任意直接依赖
+	onFailure []string
+}
+
+func (r *Dag[K, V]) setKind(nodeName string, kind nodeKind) {
+	kinds := make(map[string]nodeKind)
+	w, _ := r.kinds.Load().(map[string]nodeKind)
+	for k, v := range w {
+		kinds[k] = v
+	}
+	kinds[nodeName] = kind
+	r.kinds.Store(kinds)
+}
+
+func (r *Dag[K, V]) getKinds() map[string]nodeKind {
+	w, _ := r.kinds.Load().(map[string]nodeKind)
+	return w
+}
+
+// SetFinally 标记清理节点: 依赖都结束后总会执行, 无论上游成功, 失败还是运行被取消.
+// 处理函数中 State.Err 为运行中第一个错误, ctx 不会因为运行失败而取消
+func (r *Dag[K, V]) SetFinally(nodeNames ...string) {
+	for _, name := range nodeNames {
+		r.setKind(name, nodeKind{finally: true})
+	}
+}
+
+// SetOnFailure 标记错误处理节点: 只在 upstream 中某个节点失败时执行, State.Err 为该节点的错误,
+// 否则跳过. upstream 必须是节点的依赖, 为空时表示任意直接依赖
+func (r *Dag[K, V]) SetOnFailure(nodeName string, upstream ...string) {
+	r.setKind(nodeName, nodeKind{onFailure: append([]string{}, upstream...)})
+}
+
+func (k nodeKind) special() bool {
+	return k.finally || k.onFailure != nil
+}
+
+// shouldDispatch 运行失败或被取消后只执行清理和错误处理节点
+func (r *ExecuteState[K, V]) shouldDispatch(node *graph.Node, failing bool) bool {
+	return !failing || r.kinds[node.Name].special()
+}
+
+// nodeContext 清理和错误处理节点不随运行失败取消
+func (r *ExecuteState[K, V]) nodeContext(ctx context.Context, node *graph.Node) context.Context {
+	if r.kinds[node.Name].special() {
+		return context.WithoutCancel(ctx)
+	}
+	return ctx
+}
+
+// setFailure 记录第一个错误, 调用方持有写锁
+func (r *ExecuteState[K, V]) setFailure(err error) {
+	if r.failure == nil && err != nil {
+		r.failure = err
+	}
+}
+
+// notReached 不执行节点, 只通知调度器该节点已结束
+func (r *ExecuteState[K, V]) notReached(node *graph.Node) {
+	r.sendChan(r.Recv, node.Id)
+}
+
+// specialError 返回传给清理或错误处理节点的错误, 错误处理节点的上游都没有失败时 skip 为 true.
+// 调用方持有读锁
+func (r *ExecuteState[K, V]) specialError(node *graph.Node) (err error, skip bool) {
+	kind, ok := r.kinds[node.Name]
+	if !ok {
+		return nil, false
+	}
+	if kind.finally {
+		return r.failure, false
+	}
+	if kind.onFailure == nil {
+		return nil, false
+	}
+	var first *NodeOutput[V]
+	for _, id := range r.watches[node.Id] {
+		out, _ := r.NodeResult[id].(*NodeOutput[V])
+		if out != nil && out.Err != nil && (first == nil || out.Order < first.Order) {
+			first = out
+		}
+	}
+	if first == nil {
+		return nil, true
+	}
+	return first.Err, false
+}
+
+// failureWatches 运行开始前把错误处理节点关注的上游解析为节点 id,
+// 运行中不再按名字查找, 避免并发的运行写共享图的名字索引
+func failureWatches(g *graph.Graph, kinds map[string]nodeKind) map[uint32][]uint32 {
+	var ret map[uint32][]uint32
+	var ids map[string]uint32
+	for _, n := range g.NodeIdMapping {
+		kind := kinds[n.Name]
+		if kind.onFailure == nil {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[uint32][]uint32)
+		}
+		if len(kind.onFailure) == 0 {
+			ret[n.Id] = g.GetDepend(n.Id)
+			continue
+		}
+		if ids == nil {
+			ids = make(map[string]uint32, len(g.NodeIdMapping))
+			for _, v := range g.NodeIdMapping {
+				ids[v.Name] = v.Id
+			}
+		}
+		watch := []uint32{}
+		for _, name := range kind.onFailure {
+			if id, ok := ids[name]; ok {
+				watch = append(watch, id)
+			}
+		}
+		ret[n.Id] = watch
+	}
+	return ret
+}
@@ -0,0 +1,153 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+	"github.com/opengeektech/go-dag/graph/graphview"
+)
+
+func TestFinally(t *testing.T) {
+	errWork := errors.New("work failed")
+	type record struct {
+		err    error
+		ctxErr error
+	}
+	newJob := func(fail bool) (*Dag[int, string], map[string]record) {
+		d := New[int, string]().SetGraph(graph.NewGraph(
+			graph.WithNodes(
+				&graph.Node{Name: "lock"},
+				&graph.Node{Name: "work"},
+				&graph.Node{Name: "report"},
+				&graph.Node{Name: "alert"},
+				&graph.Node{Name: "cleanup"},
+			),
+			graph.WithDependOn("work", "lock"),
+			graph.WithDependOn("report", "work"),
+			graph.WithDependOn("alert", "work"),
+			graph.WithDependOn("cleanup", "report", "alert"),
+		))
+		var protect sync.Mutex
+		ran := make(map[string]record)
+		d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, string] {
+			return func(ctx context.Context, s *State[int, string]) (string, error) {
+				protect.Lock()
+				ran[nodeName] = record{err: s.Err, ctxErr: ctx.Err()}
+				protect.Unlock()
+				if nodeName == "work" && fail {
+					return "", errWork
+				}
+				return nodeName, nil
+			}
+		})
+		d.SetFinally("cleanup")
+		d.SetOnFailure("alert", "work")
+		return d, ran
+	}
+	t.Run("failed", func(t *testing.T) {
+		for _, async := range []bool{true, false} {
+			d, ran := newJob(true)
+			var res *RunResult[string]
+			if async {
+				res = d.RunAsyncResult(context.TODO(), 1)
+			} else {
+				res = d.RunSyncResult(context.TODO(), 1)
+			}
+			if !errors.Is(res.Err, errWork) {
+				t.Fatal(res.Err)
+			}
+			expect := map[string]graph.NodeStatus{
+				"lock":    graph.StatusSucceeded,
+				"work":    graph.StatusFailed,
+				"report":  graph.StatusNotReached,
+				"alert":   graph.StatusSucceeded,
+				"cleanup": graph.StatusSucceeded,
+			}
+			for name, status := range expect {
+				if res.Nodes[name].Status != status {
+					t.Fatal(async, name, res.Nodes[name].Status)
+				}
+			}
+			for _, name := range []string{"alert", "cleanup"} {
+				if r := ran[name]; !errors.Is(r.err, errWork) || r.ctxErr != nil {
+					t.Fatal(name, r)
+				}
+			}
+		}
+	})
+	t.Run("succeeded", func(t *testing.T) {
+		d, ran := newJob(false)
+		res := d.RunAsyncResult(context.TODO(), 1)
+		if res.Err != nil || res.Output != "cleanup" {
+			t.Fatal(res.Output, res.Err)
+		}
+		if res.Nodes["alert"].Status != graph.StatusSkipped || res.Nodes["report"].Status != graph.StatusSucceeded {
+			t.Fatal(res.Nodes["alert"].Status)
+		}
+		if r, ok := ran["cleanup"]; !ok || r.err != nil {
+			t.Fatal(r)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		d, ran := newJob(false)
+		d.SetSignal("report", SignalConfig{})
+		h := d.Submit(context.TODO(), 1)
+		waitStatus(t, h, func(st RunStatus) bool { return len(st.Waiting) == 1 })
+		h.Cancel(nil)
+		res := h.Wait()
+		if !errors.Is(res.Err, context.Canceled) || res.Nodes["cleanup"].Status != graph.StatusSucceeded {
+			t.Fatal(res.Err, res.Nodes["cleanup"].Status)
+		}
+		if r := ran["cleanup"]; !errors.Is(r.err, context.Canceled) || r.ctxErr != nil {
+			t.Fatal(r)
+		}
+	})
+	t.Run("any upstream", func(t *testing.T) {
+		d, _ := newJob(true)
+		d.SetOnFailure("alert")
+		if res := d.RunSyncResult(context.TODO(), 1); res.Nodes["alert"].Status != graph.StatusSucceeded {
+			t.Fatal(res.Nodes["alert"].Status)
+		}
+	})
+}
+
+func TestOnFailure_DecodedGraph(t *testing.T) {
+	var h graphview.JsonDecoder
+	graphs, err := h.Decode(strings.NewReader(`{"graphList": [{"graphName": "alerts", "nodes": [
+		{"id": 1, "name": "work"},
+		{"id": 2, "name": "alert", "dependOnName": ["work"]},
+		{"id": 3, "name": "page", "dependOnName": ["work"]}
+	]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errWork := errors.New("work failed")
+	d := New[int, string]().SetGraph(graphs[0])
+	d.SetFunc("work", func(ctx context.Context, s *State[int, string]) (string, error) {
+		return "", errWork
+	})
+	for _, name := range []string{"alert", "page"} {
+		d.SetFunc(name, func(ctx context.Context, s *State[int, string]) (string, error) {
+			return s.Err.Error(), nil
+		})
+	}
+	d.SetOnFailure("alert", "work")
+	d.SetOnFailure("page")
+	// 解码得到的图没有名字索引, 并发运行不能在运行中写共享的图
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := d.RunAsyncResult(context.TODO(), i)
+			if !errors.Is(res.Err, errWork) || res.Nodes["alert"].Status != graph.StatusSucceeded || res.Nodes["page"].Status != graph.StatusSucceeded {
+				t.Error(res.Err, res.Nodes["alert"].Status, res.Nodes["page"].Status)
+			}
+		}()
+	}
+	wg.Wait()
+}
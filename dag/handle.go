@@ -0,0 +1,160 @@
+package dag
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// RunStatus 某一时刻运行的快照
+type RunStatus struct {
+	ID        string
+	Start     time.Time
+	Done      bool
+	Err       error
+	Nodes     map[string]graph.NodeStatus
+	Completed int
+	Total     int
+	Percent   float64
+	Paused    bool
+	// PausedAt 由断点触发暂停时为断点节点名
+	PausedAt string
+	// Waiting 正在等待信号的节点
+	Waiting []string
+}
+
+type runConfig struct {
+	breakpoints []string
+}
+
+type RunOption func(*runConfig)
+
+// WithBreakpoint 在这些节点派发前暂停运行, 需要 Resume 才能继续
+func WithBreakpoint(nodes ...string) RunOption {
+	return func(c *runConfig) {
+		c.breakpoints = append(c.breakpoints, nodes...)
+	}
+}
+
+// RunHandle Submit 返回的后台运行句柄
+type RunHandle[K, V any] struct {
+	id     string
+	start  time.Time
+	state  *ExecuteState[K, V]
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	result *RunResult[V]
+}
+
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Submit 在后台并发运行, 立即返回句柄. 运行结束前可以通过 GetRun 按 ID 找到
+func (r *Dag[K, V]) Submit(ctx context.Context, k K, opts ...RunOption) *RunHandle[K, V] {
+	var c runConfig
+	for _, fn := range opts {
+		fn(&c)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := r.newExecuteState()
+	w.ensure()
+	w.gate = newPauseGate(c.breakpoints)
+	h := &RunHandle[K, V]{
+		id:     w.RunID,
+		start:  time.Now(),
+		state:  w,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	r.runs.Store(h.id, h)
+	go func() {
+		defer close(h.done)
+		defer r.runs.Delete(h.id)
+		defer cancel(nil)
+		v, err := r.run(ctx, w, k, true)
+		if cause := context.Cause(ctx); err != nil && cause != nil {
+			err = cause
+		}
+		h.result = w.result(v, err)
+		r.release(w)
+	}()
+	return h
+}
+
+// GetRun 返回仍在运行的句柄
+func (r *Dag[K, V]) GetRun(id string) (*RunHandle[K, V], bool) {
+	h, ok := r.runs.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return h.(*RunHandle[K, V]), true
+}
+
+// Runs 返回正在运行的 ID
+func (r *Dag[K, V]) Runs() []string {
+	var ret []string
+	r.runs.Range(func(key, value any) bool {
+		ret = append(ret, key.(string))
+		return true
+	})
+	sort.Strings(ret)
+	return ret
+}
+
+func (h *RunHandle[K, V]) ID() string {
+	return h.id
+}
+
+func (h *RunHandle[K, V]) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait 等待运行结束并返回结果
+func (h *RunHandle[K, V]) Wait() *RunResult[V] {
+	<-h.done
+	return h.result
+}
+
+// Cancel 取消运行, cause 为 nil 时结果中的错误为 context.Canceled
+func (h *RunHandle[K, V]) Cancel(cause error) {
+	h.cancel(cause)
+}
+
+// Status 返回当前各节点的状态和完成比例, 可以在运行中随时调用
+func (h *RunHandle[K, V]) Status() RunStatus {
+	st := RunStatus{ID: h.id, Start: h.start, Nodes: make(map[string]graph.NodeStatus)}
+	var nodes map[string]*NodeReport
+	select {
+	case <-h.done:
+		st.Done = true
+		st.Err = h.result.Err
+		nodes = h.result.Nodes
+	default:
+		nodes = h.state.result(*new(V), nil).Nodes
+		st.Paused, st.PausedAt = h.state.gate.state()
+		st.Waiting = h.state.waitingSignals()
+	}
+	for name, v := range nodes {
+		status := v.Status
+		if !st.Done && status == graph.StatusNotReached {
+			status = graph.StatusPending
+		}
+		switch status {
+		case graph.StatusSucceeded, graph.StatusFailed, graph.StatusSkipped:
+			st.Completed++
+		}
+		st.Nodes[name] = status
+	}
+	st.Total = len(nodes)
+	if st.Total > 0 {
+		st.Percent = float64(st.Completed) * 100 / float64(st.Total)
+	}
+	return st
+}
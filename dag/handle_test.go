@@ -0,0 +1,64 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func TestSubmit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "d" {
+				close(started)
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return 0, ctx.Err()
+				}
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		h := d.Submit(context.TODO(), 1)
+		<-started
+		st := h.Status()
+		if st.Done || st.Completed != 3 || st.Total != 4 || st.Percent != 75 || st.Nodes["d"] != graph.StatusRunning {
+			t.Fatal(st)
+		}
+		if got, ok := d.GetRun(h.ID()); !ok || got != h || len(d.Runs()) != 1 {
+			t.Fatal("run not registered")
+		}
+		close(release)
+		res := h.Wait()
+		if res.Err != nil || res.Output != 3 {
+			t.Fatal(res.Output, res.Err)
+		}
+		<-h.Done()
+		if st := h.Status(); !st.Done || st.Percent != 100 {
+			t.Fatal(st)
+		}
+		if _, ok := d.GetRun(h.ID()); ok {
+			t.Fatal("finished run still registered")
+		}
+	})
+	t.Run("cancel", func(t *testing.T) {
+		started = make(chan struct{})
+		release = make(chan struct{})
+		errStop := errors.New("stop")
+		h := d.Submit(context.TODO(), 1)
+		<-started
+		h.Cancel(errStop)
+		res := h.Wait()
+		if !errors.Is(res.Err, errStop) || res.Nodes["d"].Status != graph.StatusFailed {
+			t.Fatal(res.Err, res.Nodes["d"].Status)
+		}
+	})
+}
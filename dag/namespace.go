@@ -0,0 +1,153 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrDagTypeMismatch = errors.New("dag type mismatch")
+)
+
+// DagInfo 注册表中一个 dag 的名字和类型参数
+type DagInfo struct {
+	Namespace string
+	Name      string
+	Input     string
+	Output    string
+}
+
+type namespaceEntry struct {
+	dag    any
+	input  reflect.Type
+	output reflect.Type
+}
+
+// Namespace 独立的 dag 注册表, 库可以使用自己的 Namespace 避免与其他库重名
+type Namespace struct {
+	name    string
+	protect sync.RWMutex
+	dags    map[string]namespaceEntry
+	hooks   []func(DagInfo)
+}
+
+// DefaultNamespace SetGlobalDag/GetGlobalDag/Run 使用的注册表
+var DefaultNamespace = NewNamespace("default")
+
+func NewNamespace(name string) *Namespace {
+	return &Namespace{name: name, dags: make(map[string]namespaceEntry)}
+}
+
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+// OnUnregister 注册回调, dag 被移除或被同名 dag 替换时调用
+func (ns *Namespace) OnUnregister(fn func(DagInfo)) {
+	ns.protect.Lock()
+	defer ns.protect.Unlock()
+	ns.hooks = append(ns.hooks, fn)
+}
+
+func (ns *Namespace) info(name string, e namespaceEntry) DagInfo {
+	return DagInfo{Namespace: ns.name, Name: name, Input: e.input.String(), Output: e.output.String()}
+}
+
+// store 在锁内替换条目, 锁外调用回调. 重复注册同一个 dag 不调用回调
+func (ns *Namespace) store(name string, e *namespaceEntry) bool {
+	ns.protect.Lock()
+	old, ok := ns.dags[name]
+	if e == nil {
+		delete(ns.dags, name)
+	} else {
+		ns.dags[name] = *e
+	}
+	hooks := ns.hooks
+	ns.protect.Unlock()
+	if ok && (e == nil || e.dag != old.dag) {
+		info := ns.info(name, old)
+		for _, fn := range hooks {
+			fn(info)
+		}
+	}
+	return ok
+}
+
+// Unregister 移除 name, 返回是否存在
+func (ns *Namespace) Unregister(name string) bool {
+	return ns.store(name, nil)
+}
+
+// List 按名字排序返回所有 dag
+func (ns *Namespace) List() []DagInfo {
+	ns.protect.RLock()
+	defer ns.protect.RUnlock()
+	ret := make([]DagInfo, 0, len(ns.dags))
+	for name, e := range ns.dags {
+		ret = append(ret, ns.info(name, e))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// Register 把 d 以 name 注册到 ns, d 为 nil 时等同于 Unregister
+func Register[K, V any](ns *Namespace, name string, d *Dag[K, V]) {
+	if d == nil {
+		ns.Unregister(name)
+		return
+	}
+	ns.store(name, &namespaceEntry{dag: d, input: reflect.TypeFor[K](), output: reflect.TypeFor[V]()})
+}
+
+// Lookup 查找 name, 不存在返回 ErrDagNotFound, 类型参数不一致返回 ErrDagTypeMismatch
+func Lookup[K, V any](ns *Namespace, name string) (*Dag[K, V], error) {
+	ns.protect.RLock()
+	e, ok := ns.dags[name]
+	ns.protect.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrDagNotFound, ns.name, name)
+	}
+	d, ok := e.dag.(*Dag[K, V])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s is Dag[%s, %s], not Dag[%s, %s]", ErrDagTypeMismatch,
+			ns.name, name, e.input, e.output, reflect.TypeFor[K](), reflect.TypeFor[V]())
+	}
+	return d, nil
+}
+
+func RunIn[K, V any](ns *Namespace, name string, ctx context.Context, params K) (V, error) {
+	dg, err := Lookup[K, V](ns, name)
+	if err != nil {
+		var e V
+		return e, err
+	}
+	if dg.graph.Load() == nil {
+		var e V
+		return e, fmt.Errorf("%w: %s/%s has no graph", ErrDagNotFound, ns.name, name)
+	}
+	return dg.RunSync(ctx, params)
+}
+
+func RunAsyncIn[K, V any](ns *Namespace, name string, ctx context.Context, params K) (V, error) {
+	dg, err := Lookup[K, V](ns, name)
+	if err != nil {
+		var e V
+		return e, err
+	}
+	if dg.graph.Load() == nil {
+		var e V
+		return e, fmt.Errorf("%w: %s/%s has no graph", ErrDagNotFound, ns.name, name)
+	}
+	return dg.RunAsync(ctx, params)
+}
+
+// ListGlobalDags 列出默认注册表中的 dag
+func ListGlobalDags() []DagInfo {
+	return DefaultNamespace.List()
+}
@@ -0,0 +1,50 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	ns := NewNamespace("lib")
+	var removed []DagInfo
+	ns.OnUnregister(func(info DagInfo) {
+		removed = append(removed, info)
+	})
+	Register(ns, "flow", newConstDag(3))
+	Register(ns, "named", New[string, error]())
+
+	if v, err := RunIn[int, int](ns, "flow", context.TODO(), 0); err != nil || v != 3 {
+		t.Fatal(v, err)
+	}
+	if _, err := RunIn[string, int](ns, "flow", context.TODO(), ""); !errors.Is(err, ErrDagTypeMismatch) {
+		t.Fatal(err)
+	}
+	if _, err := Lookup[int, int](ns, "missing"); !errors.Is(err, ErrDagNotFound) {
+		t.Fatal(err)
+	}
+	if _, err := RunIn[string, error](ns, "named", context.TODO(), ""); !errors.Is(err, ErrDagNotFound) {
+		t.Fatal("dag without graph", err)
+	}
+	list := ns.List()
+	if len(list) != 2 || list[0] != (DagInfo{Namespace: "lib", Name: "flow", Input: "int", Output: "int"}) ||
+		list[1].Output != "error" {
+		t.Fatal(list)
+	}
+	// 默认注册表不受影响
+	if _, err := Lookup[int, int](DefaultNamespace, "flow"); !errors.Is(err, ErrDagNotFound) {
+		t.Fatal(err)
+	}
+
+	flow := newConstDag(4)
+	Register(ns, "flow", flow)
+	// 重复注册同一个 dag 不算替换
+	Register(ns, "flow", flow)
+	if !ns.Unregister("named") || ns.Unregister("named") {
+		t.Fatal("unregister")
+	}
+	if len(removed) != 2 || removed[0].Name != "flow" || removed[1].Name != "named" {
+		t.Fatal(removed)
+	}
+}
@@ -0,0 +1,122 @@
+package dag
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrRunNotFound = errors.New("run not found")
+)
+
+// pauseGate 控制调度器是否继续派发新的节点, 已经在执行的节点不受影响
+type pauseGate struct {
+	protect     sync.Mutex
+	paused      bool
+	pausedAt    string
+	resume      chan struct{}
+	breakpoints map[string]struct{}
+}
+
+func newPauseGate(breakpoints []string) *pauseGate {
+	g := &pauseGate{breakpoints: make(map[string]struct{})}
+	for _, v := range breakpoints {
+		g.breakpoints[v] = struct{}{}
+	}
+	return g
+}
+
+// wait 返回派发 node 前需要等待的 channel, 未暂停时返回 nil.
+// 命中断点时暂停, 断点只生效一次
+func (g *pauseGate) wait(node string) <-chan struct{} {
+	if g == nil {
+		return nil
+	}
+	g.protect.Lock()
+	defer g.protect.Unlock()
+	if _, ok := g.breakpoints[node]; ok && !g.paused {
+		delete(g.breakpoints, node)
+		g.pauseLocked(node)
+	}
+	if !g.paused {
+		return nil
+	}
+	return g.resume
+}
+
+func (g *pauseGate) pauseLocked(at string) {
+	if g.paused {
+		return
+	}
+	g.paused = true
+	g.pausedAt = at
+	g.resume = make(chan struct{})
+}
+
+func (g *pauseGate) pause() {
+	g.protect.Lock()
+	defer g.protect.Unlock()
+	g.pauseLocked("")
+}
+
+func (g *pauseGate) unpause() {
+	g.protect.Lock()
+	defer g.protect.Unlock()
+	if !g.paused {
+		return
+	}
+	g.paused = false
+	g.pausedAt = ""
+	close(g.resume)
+}
+
+func (g *pauseGate) addBreakpoint(node string) {
+	g.protect.Lock()
+	defer g.protect.Unlock()
+	g.breakpoints[node] = struct{}{}
+}
+
+// state 返回是否暂停, 以及由哪个断点触发
+func (g *pauseGate) state() (bool, string) {
+	g.protect.Lock()
+	defer g.protect.Unlock()
+	return g.paused, g.pausedAt
+}
+
+// Pause 停止派发新的就绪节点, 正在执行的节点继续运行至结束
+func (h *RunHandle[K, V]) Pause() {
+	h.state.gate.pause()
+}
+
+func (h *RunHandle[K, V]) Resume() {
+	h.state.gate.unpause()
+}
+
+func (h *RunHandle[K, V]) Paused() bool {
+	paused, _ := h.state.gate.state()
+	return paused
+}
+
+// PauseAt 在节点 node 即将派发时暂停, 节点已经派发过则不生效
+func (h *RunHandle[K, V]) PauseAt(node string) {
+	h.state.gate.addBreakpoint(node)
+}
+
+// PauseRun 按运行 ID 暂停, 运行不存在或已结束时返回 ErrRunNotFound
+func (r *Dag[K, V]) PauseRun(id string) error {
+	h, ok := r.GetRun(id)
+	if !ok {
+		return ErrRunNotFound
+	}
+	h.Pause()
+	return nil
+}
+
+func (r *Dag[K, V]) ResumeRun(id string) error {
+	h, ok := r.GetRun(id)
+	if !ok {
+		return ErrRunNotFound
+	}
+	h.Resume()
+	return nil
+}
@@ -0,0 +1,82 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func waitStatus[K, V any](t *testing.T, h *RunHandle[K, V], fn func(RunStatus) bool) RunStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if st := h.Status(); fn(st) {
+			return st
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timeout", h.Status())
+	return RunStatus{}
+}
+
+func TestPause(t *testing.T) {
+	release := make(chan struct{})
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "a" {
+				<-release
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+
+	t.Run("breakpoint", func(t *testing.T) {
+		close(release)
+		defer func() { release = make(chan struct{}) }()
+		h := d.Submit(context.TODO(), 1, WithBreakpoint("d"))
+		waitStatus(t, h, func(st RunStatus) bool { return st.Paused })
+		st := waitStatus(t, h, func(st RunStatus) bool { return st.Completed == 3 })
+		if st.PausedAt != "d" || st.Nodes["d"] != graph.StatusPending {
+			t.Fatal(st)
+		}
+		if err := d.ResumeRun(h.ID()); err != nil {
+			t.Fatal(err)
+		}
+		if res := h.Wait(); res.Err != nil || res.Output != 3 {
+			t.Fatal(res.Output, res.Err)
+		}
+		if err := d.PauseRun(h.ID()); !errors.Is(err, ErrRunNotFound) {
+			t.Fatal(err)
+		}
+	})
+	t.Run("pause", func(t *testing.T) {
+		h := d.Submit(context.TODO(), 1)
+		waitStatus(t, h, func(st RunStatus) bool { return st.Nodes["a"] == graph.StatusRunning })
+		if err := d.PauseRun(h.ID()); err != nil || !h.Paused() {
+			t.Fatal(err)
+		}
+		// 正在执行的节点可以结束, 之后不再派发
+		close(release)
+		waitStatus(t, h, func(st RunStatus) bool { return st.Completed == 1 })
+		time.Sleep(20 * time.Millisecond)
+		if st := h.Status(); st.Completed != 1 || st.Nodes["b"] != graph.StatusPending {
+			t.Fatal(st)
+		}
+		h.Resume()
+		if res := h.Wait(); res.Err != nil || res.Output != 3 {
+			t.Fatal(res.Output, res.Err)
+		}
+	})
+	t.Run("cancel while paused", func(t *testing.T) {
+		h := d.Submit(context.TODO(), 1, WithBreakpoint("b", "c"))
+		waitStatus(t, h, func(st RunStatus) bool { return st.Paused })
+		h.Cancel(nil)
+		if res := h.Wait(); !errors.Is(res.Err, context.Canceled) {
+			t.Fatal(res.Err)
+		}
+	})
+}
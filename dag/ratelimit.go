@@ -0,0 +1,170 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited       = errors.New("rate limit wait exceeds context deadline")
+	ErrRateLimitConflict = errors.New("rate limit conflicts with existing bucket")
+)
+
+// RateLimit 节点令牌桶限流配置
+type RateLimit struct {
+	// Rate 每秒生成的令牌数, 不大于 0 时取消限流
+	Rate float64
+	// Burst 桶容量, 默认 1
+	Burst int
+	// Key 不为空时 Limiters 中 Key 相同的节点共享一个令牌桶, 包括其他 Dag 中的节点
+	Key string
+	// Limiters 共享令牌桶所在的集合, 为 nil 时使用 GlobalRateLimiters
+	Limiters *RateLimiters
+}
+
+// RateLimiters 按 Key 共享的令牌桶集合
+type RateLimiters struct {
+	protect sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// GlobalRateLimiters 默认的共享令牌桶集合
+var GlobalRateLimiters = NewRateLimiters()
+
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{buckets: make(map[string]*tokenBucket)}
+}
+
+// bucket 返回 key 对应的令牌桶, 已存在且配置不同时返回 ErrRateLimitConflict
+func (l *RateLimiters) bucket(key string, rate float64, burst int) (*tokenBucket, error) {
+	l.protect.Lock()
+	defer l.protect.Unlock()
+	b := l.buckets[key]
+	if b == nil {
+		b = newTokenBucket(rate, burst)
+		l.buckets[key] = b
+		return b, nil
+	}
+	if b.rate != rate || b.burst != float64(max(burst, 1)) {
+		return nil, fmt.Errorf("%w: %s is %v/s burst %v", ErrRateLimitConflict, key, b.rate, b.burst)
+	}
+	return b, nil
+}
+
+// Remove 删除共享的令牌桶, 已经设置的节点继续使用原来的令牌桶
+func (l *RateLimiters) Remove(key string) {
+	l.protect.Lock()
+	defer l.protect.Unlock()
+	delete(l.buckets, key)
+}
+
+type tokenBucket struct {
+	protect sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := &tokenBucket{rate: rate, burst: float64(max(burst, 1)), last: time.Now()}
+	b.tokens = b.burst
+	return b
+}
+
+// reserve 取一个令牌, 令牌不足时可以透支, 返回需要等待的时间
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还未使用的令牌
+func (b *tokenBucket) cancel() {
+	b.protect.Lock()
+	defer b.protect.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	now := time.Now()
+	delay := b.reserve(now)
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		b.cancel()
+		return ErrRateLimited
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return context.Cause(ctx)
+	}
+}
+
+// SetRateLimit 设置节点执行前的限流, 等待令牌的时间计入 NodeReport.QueueTime.
+// Key 已经以不同的速率或容量使用时返回 ErrRateLimitConflict
+func (r *Dag[K, V]) SetRateLimit(nodeName string, limit RateLimit) error {
+	var b *tokenBucket
+	switch {
+	case limit.Rate <= 0:
+	case limit.Key != "":
+		limiters := limit.Limiters
+		if limiters == nil {
+			limiters = GlobalRateLimiters
+		}
+		var err error
+		if b, err = limiters.bucket(limit.Key, limit.Rate, limit.Burst); err != nil {
+			return err
+		}
+	default:
+		b = newTokenBucket(limit.Rate, limit.Burst)
+	}
+	limits := make(map[string]*tokenBucket)
+	w, _ := r.rateLimits.Load().(map[string]*tokenBucket)
+	for k, v := range w {
+		limits[k] = v
+	}
+	if b == nil {
+		delete(limits, nodeName)
+	} else {
+		limits[nodeName] = b
+	}
+	r.rateLimits.Store(limits)
+	return nil
+}
+
+func (r *Dag[K, V]) getRateLimits() map[string]*tokenBucket {
+	w, _ := r.rateLimits.Load().(map[string]*tokenBucket)
+	return w
+}
+
+func rateLimitHandler[K, V any](b *tokenBucket, handler HandlerFunc[K, V], queue *time.Duration) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		start := time.Now()
+		err := b.wait(ctx)
+		*queue += time.Since(start)
+		if err != nil {
+			var e V
+			return e, err
+		}
+		return handler(ctx, s)
+	}
+}
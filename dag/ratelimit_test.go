@@ -0,0 +1,108 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	newDag := func() *Dag[int, int] {
+		d := New[int, int]().SetGraph(newDiamondGraph())
+		d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+			return func(ctx context.Context, s *State[int, int]) (int, error) {
+				return s.Input + len(s.DependNodeResult), nil
+			}
+		})
+		return d
+	}
+	t.Run("node", func(t *testing.T) {
+		d := newDag()
+		d.SetRateLimit("b", RateLimit{Rate: 50, Burst: 2})
+		start := time.Now()
+		var queued time.Duration
+		for i := 0; i < 4; i++ {
+			res := d.RunAsyncResult(context.TODO(), 1)
+			if res.Err != nil || res.Output != 3 {
+				t.Fatal(res.Err)
+			}
+			queued += res.Nodes["b"].QueueTime
+		}
+		// 前两次使用桶中的令牌, 之后每次等待约 20ms
+		if elapsed := time.Since(start); elapsed < 35*time.Millisecond || queued < 35*time.Millisecond {
+			t.Fatal(elapsed, queued)
+		}
+	})
+	t.Run("shared key", func(t *testing.T) {
+		d1, d2 := newDag(), newDag()
+		limiters := NewRateLimiters()
+		if err := d1.SetRateLimit("b", RateLimit{Rate: 10, Key: "api", Limiters: limiters}); err != nil {
+			t.Fatal(err)
+		}
+		if err := d2.SetRateLimit("c", RateLimit{Rate: 10, Key: "api", Limiters: limiters}); err != nil {
+			t.Fatal(err)
+		}
+		// 不同的配置不能修改其他 Dag 正在使用的令牌桶
+		if err := d2.SetRateLimit("b", RateLimit{Rate: 100, Key: "api", Limiters: limiters}); !errors.Is(err, ErrRateLimitConflict) {
+			t.Fatal(err)
+		}
+		if res := d1.RunAsyncResult(context.TODO(), 1); res.Err != nil || res.Nodes["b"].QueueTime > 10*time.Millisecond {
+			t.Fatal(res.Err)
+		}
+		res := d2.RunAsyncResult(context.TODO(), 1)
+		if res.Err != nil || res.Nodes["c"].QueueTime < 50*time.Millisecond {
+			t.Fatal(res.Err, res.Nodes["c"].QueueTime)
+		}
+	})
+	t.Run("deadline", func(t *testing.T) {
+		d := newDag()
+		d.SetRateLimit("b", RateLimit{Rate: 1})
+		d.RunSync(context.TODO(), 1)
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+		// 等待时间超过截止时间时直接失败, 令牌归还
+		if res := d.RunSyncResult(ctx, 1); !errors.Is(res.Err, ErrRateLimited) {
+			t.Fatal(res.Err)
+		}
+		ctx, cancel = context.WithCancel(context.TODO())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if res := d.RunAsyncResult(ctx, 1); !errors.Is(res.Err, context.Canceled) || res.Nodes["b"].QueueTime < 20*time.Millisecond {
+			t.Fatal(res.Err)
+		}
+		d.SetRateLimit("b", RateLimit{})
+		if res := d.RunSyncResult(context.TODO(), 1); res.Err != nil {
+			t.Fatal(res.Err)
+		}
+	})
+}
+
+func TestRateLimit_BreakerOpen(t *testing.T) {
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" {
+				return 0, errors.New("unavailable")
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.SetBreaker("b", BreakerConfig[int, int]{FailureThreshold: 1, CoolDown: time.Hour})
+	d.SetRateLimit("b", RateLimit{Rate: 1})
+	d.DefineResource("db", 1)
+	d.SetResources("b", map[string]int64{"db": 1})
+	if res := d.RunAsyncResult(context.TODO(), 1); res.Err == nil || res.Nodes["b"].Breaker != BreakerClosed {
+		t.Fatal(res.Err)
+	}
+	// 熔断器打开后不再等待令牌和资源
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		res := d.RunAsyncResult(context.TODO(), 1)
+		if !errors.Is(res.Err, ErrBreakerOpen) || res.Nodes["b"].QueueTime != 0 {
+			t.Fatal(res.Err, res.Nodes["b"].QueueTime)
+		}
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal(time.Since(start))
+	}
+}
@@ -0,0 +1,164 @@
+package dag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// HandlerFactory 根据节点配置的 params 创建处理函数
+type HandlerFactory[K, V any] func(params map[string]any) (HandlerFunc[K, V], error)
+
+// Registry 维护处理函数类型名到工厂的映射, 用于把图定义中的 handler 字段绑定到 Dag
+type Registry[K, V any] struct {
+	protect   sync.RWMutex
+	factories map[string]HandlerFactory[K, V]
+}
+
+var (
+	ErrHandlerNotFound = fmt.Errorf("handler type not registered")
+)
+
+func NewRegistry[K, V any]() *Registry[K, V] {
+	return &Registry[K, V]{
+		factories: make(map[string]HandlerFactory[K, V]),
+	}
+}
+
+func (r *Registry[K, V]) Register(name string, factory HandlerFactory[K, V]) *Registry[K, V] {
+	r.protect.Lock()
+	defer r.protect.Unlock()
+	if r.factories == nil {
+		r.factories = make(map[string]HandlerFactory[K, V])
+	}
+	r.factories[name] = factory
+	return r
+}
+
+// RegisterFunc 注册不需要参数的处理函数
+func (r *Registry[K, V]) RegisterFunc(name string, fn HandlerFunc[K, V]) *Registry[K, V] {
+	return r.Register(name, func(map[string]any) (HandlerFunc[K, V], error) {
+		return fn, nil
+	})
+}
+
+func (r *Registry[K, V]) Lookup(name string) (HandlerFactory[K, V], bool) {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	f, ok := r.factories[name]
+	return f, ok
+}
+
+func (r *Registry[K, V]) Names() []string {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	ret := make([]string, 0, len(r.factories))
+	for k := range r.factories {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Build 为图中所有配置了 handler 的节点创建处理函数, 返回全部绑定错误
+func (r *Registry[K, V]) Build(g *graph.Graph) (map[string]HandlerFunc[K, V], error) {
+	funcMap := make(map[string]HandlerFunc[K, V])
+	var errs []error
+	for _, node := range g.Nodes() {
+		if node.Handler == "" {
+			continue
+		}
+		factory, ok := r.Lookup(node.Handler)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: node %s handler %s", ErrHandlerNotFound, node.Name, node.Handler))
+			continue
+		}
+		fn, err := factory(node.Params)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s handler %s: %w", node.Name, node.Handler, err))
+			continue
+		}
+		funcMap[node.Name] = fn
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return funcMap, nil
+}
+
+// DecodeParams 把节点 params 转换为具体的配置结构
+func DecodeParams[T any](params map[string]any) (T, error) {
+	var ret T
+	if len(params) == 0 {
+		return ret, nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(b, &ret)
+	return ret, err
+}
+
+// Bind 使用 registry 为当前图的节点设置处理函数, 已通过 SetFunc 设置且未配置 handler 的节点保持不变
+func (r *Dag[K, V]) Bind(reg *Registry[K, V]) error {
+	if r.graph.Load() == nil {
+		return ErrDagNotFound
+	}
+	funcs, err := reg.Build(r.graph.Load())
+	if err != nil {
+		return err
+	}
+	r.registry = reg
+	r.bindFuncs(funcs)
+	return nil
+}
+
+func (r *Dag[K, V]) setExplicit(funcs map[string]HandlerFunc[K, V]) {
+	explicit := make(map[string]HandlerFunc[K, V])
+	w, _ := r.explicit.Load().(map[string]HandlerFunc[K, V])
+	for k, v := range w {
+		explicit[k] = v
+	}
+	for k, v := range funcs {
+		explicit[k] = v
+	}
+	r.explicit.Store(explicit)
+}
+
+// bindFuncs 用 registry 构建的处理函数替换上一次绑定的结果, 新图中不再配置 handler 的节点
+// 回到 SetFunc 设置的处理函数或没有处理函数
+func (r *Dag[K, V]) bindFuncs(funcs map[string]HandlerFunc[K, V]) {
+	funcMap := make(map[string]HandlerFunc[K, V])
+	w, _ := r.explicit.Load().(map[string]HandlerFunc[K, V])
+	for k, v := range w {
+		funcMap[k] = v
+	}
+	for k, v := range funcs {
+		funcMap[k] = v
+	}
+	r.funcMap.Store(funcMap)
+}
+
+func WithRegistry[K, V any](reg *Registry[K, V]) Option[K, V] {
+	return func(d *Dag[K, V]) {
+		d.registry = reg
+	}
+}
+
+// NewWithRegistry 由解码得到的图和 registry 直接创建可运行的 Dag
+func NewWithRegistry[K, V any](g *graph.Graph, reg *Registry[K, V], opts ...Option[K, V]) (*Dag[K, V], error) {
+	d := New[K, V]()
+	d.SetGraph(g)
+	for _, fn := range opts {
+		fn(d)
+	}
+	if err := d.Bind(reg); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,117 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+	"github.com/opengeektech/go-dag/graph/graphview"
+)
+
+const registryGraph = `{
+	"graphList": [{
+		"graphName": "calc",
+		"nodes": [
+			{"name": "start", "handler": "add", "params": {"n": 1}},
+			{"name": "double", "dependOnName": ["start"], "handler": "mul", "params": {"n": 2}},
+			{"name": "end", "dependOnName": ["double"], "handler": "add", "params": {"n": 3}}
+		]
+	}]
+}`
+
+func newCalcRegistry() *Registry[int, int] {
+	type param struct {
+		N int `json:"n"`
+	}
+	reg := NewRegistry[int, int]()
+	reg.Register("add", func(params map[string]any) (HandlerFunc[int, int], error) {
+		p, err := DecodeParams[param](params)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if len(s.DependNodeResult) == 0 {
+				return s.Input + p.N, nil
+			}
+			return s.Last + p.N, nil
+		}, nil
+	})
+	reg.Register("mul", func(params map[string]any) (HandlerFunc[int, int], error) {
+		p, err := DecodeParams[param](params)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			return s.Last * p.N, nil
+		}, nil
+	})
+	return reg
+}
+
+func TestRegistry_Bind(t *testing.T) {
+	var h graphview.JsonDecoder
+	graphs, err := h.Decode(strings.NewReader(registryGraph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("run", func(t *testing.T) {
+		d, err := NewWithRegistry(graphs[0], newCalcRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Name() != "calc" {
+			t.Error("name ", d.Name())
+		}
+		// (1+1)*2+3
+		v, err := d.RunSync(context.TODO(), 1)
+		if err != nil || v != 7 {
+			t.Fatal(v, err)
+		}
+		v, err = d.RunAsync(context.TODO(), 1)
+		if err != nil || v != 7 {
+			t.Fatal(v, err)
+		}
+	})
+	t.Run("missing_handler", func(t *testing.T) {
+		reg := NewRegistry[int, int]()
+		_, err := NewWithRegistry(graphs[0], reg)
+		if !errors.Is(err, ErrHandlerNotFound) {
+			t.Fatal("expect not found ", err)
+		}
+		if strings.Count(err.Error(), "\n") != 2 {
+			t.Error("expect all 3 nodes reported ", err)
+		}
+	})
+	t.Run("reload", func(t *testing.T) {
+		d, err := NewWithRegistry(graphs[0], newCalcRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetFunc("extra", func(ctx context.Context, s *State[int, int]) (int, error) {
+			return 0, nil
+		})
+		// double 改名为 twice, end 不再配置 handler
+		g := graph.NewGraph(graph.WithNodes(
+			&graph.Node{Name: "start", Handler: "add"},
+			&graph.Node{Name: "twice", Handler: "mul"},
+			&graph.Node{Name: "end"},
+			&graph.Node{Name: "extra"},
+		))
+		if err := d.Reload(g); err != nil {
+			t.Fatal(err)
+		}
+		funcs := d.getFuncMap()
+		for _, name := range []string{"start", "twice", "extra"} {
+			if funcs[name] == nil {
+				t.Error("missing ", name)
+			}
+		}
+		for _, name := range []string{"double", "end"} {
+			if funcs[name] != nil {
+				t.Error("stale handler ", name)
+			}
+		}
+	})
+}
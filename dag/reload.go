@@ -0,0 +1,221 @@
+package dag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+	"github.com/opengeektech/go-dag/graph/graphview"
+)
+
+// Reloadable 可以由 Reloader 替换图的对象, *Dag 实现了该接口
+type Reloadable interface {
+	Reload(g *graph.Graph) error
+}
+
+// Reload 校验新图后原子替换当前图, 已经开始的运行继续使用开始时的图和处理函数.
+// 配置了 registry 时按新图重新构建处理函数, 上一版本绑定的处理函数不再保留, SetFunc 设置的保持不变.
+// 构建失败则保持原状
+func (r *Dag[K, V]) Reload(g *graph.Graph) error {
+	if g == nil {
+		return ErrDagNotFound
+	}
+	if _, err := g.Levels(); err != nil {
+		return err
+	}
+	var funcs map[string]HandlerFunc[K, V]
+	if r.registry != nil {
+		var err error
+		if funcs, err = r.registry.Build(g); err != nil {
+			return err
+		}
+	}
+	r.reload.Lock()
+	defer r.reload.Unlock()
+	if r.registry != nil {
+		r.bindFuncs(funcs)
+	}
+	r.graph.Store(g)
+	return nil
+}
+
+type reloadTarget struct {
+	graphName string
+	dst       Reloadable
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+	targets []reloadTarget
+}
+
+// Reloader 轮询图定义文件, 内容变化时重新解码并替换已注册对象的图.
+// 解码或校验失败时保留上一个可用版本, 并通过 OnError 报告
+type Reloader struct {
+	Interval time.Duration
+	Strict   bool
+	OnReload func(path string, g *graph.Graph)
+	OnError  func(path string, err error)
+
+	protect  sync.Mutex
+	checking sync.Mutex
+	files    map[string]*watchedFile
+}
+
+func NewReloader(interval time.Duration) *Reloader {
+	return &Reloader{Interval: interval}
+}
+
+// Watch 立即加载 path 中名为 graphName 的图到 dst, 之后文件变化时自动重新加载.
+// graphName 为空时文件中只能有一个图
+func (r *Reloader) Watch(path, graphName string, dst Reloadable) error {
+	st, b, err := readWatched(path)
+	if err != nil {
+		return err
+	}
+	target := reloadTarget{graphName: graphName, dst: dst}
+	graphs, err := r.decode(path, b)
+	if err == nil {
+		err = target.apply(graphs)
+	}
+	if err != nil {
+		return err
+	}
+	r.protect.Lock()
+	defer r.protect.Unlock()
+	if r.files == nil {
+		r.files = make(map[string]*watchedFile)
+	}
+	f := r.files[path]
+	if f == nil {
+		f = &watchedFile{modTime: st.ModTime(), size: st.Size(), sum: sha256.Sum256(b)}
+		r.files[path] = f
+	}
+	f.targets = append(f.targets, target)
+	return nil
+}
+
+// Check 检查一次所有文件, 通常由 Run 定时调用
+func (r *Reloader) Check() {
+	r.checking.Lock()
+	defer r.checking.Unlock()
+	r.protect.Lock()
+	paths := make(map[string]watchedFile, len(r.files))
+	for path, f := range r.files {
+		paths[path] = *f
+	}
+	r.protect.Unlock()
+	for path, f := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			r.report(path, err)
+			continue
+		}
+		if st.ModTime().Equal(f.modTime) && st.Size() == f.size {
+			continue
+		}
+		st, b, err := readWatched(path)
+		if err != nil {
+			r.report(path, err)
+			continue
+		}
+		sum := sha256.Sum256(b)
+		r.protect.Lock()
+		if w := r.files[path]; w != nil {
+			// 失败的内容同样记录下来, 直到文件再次变化才重试
+			w.modTime, w.size, w.sum = st.ModTime(), st.Size(), sum
+		}
+		r.protect.Unlock()
+		if sum == f.sum {
+			continue
+		}
+		graphs, err := r.decode(path, b)
+		if err != nil {
+			r.report(path, err)
+			continue
+		}
+		for _, t := range f.targets {
+			if err := t.apply(graphs); err != nil {
+				r.report(path, err)
+				continue
+			}
+			if r.OnReload != nil {
+				r.OnReload(path, selectGraph(graphs, t.graphName))
+			}
+		}
+	}
+}
+
+// Run 每隔 Interval 检查一次, 直到 ctx 结束
+func (r *Reloader) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.Check()
+		}
+	}
+}
+
+func (r *Reloader) report(path string, err error) {
+	if r.OnError != nil {
+		r.OnError(path, err)
+	}
+}
+
+func (r *Reloader) decode(path string, b []byte) ([]*graph.Graph, error) {
+	dec, err := graphview.DecoderFor(path, r.Strict)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decode(bytes.NewReader(b))
+}
+
+func (t reloadTarget) apply(graphs []*graph.Graph) error {
+	g := selectGraph(graphs, t.graphName)
+	if g == nil {
+		if t.graphName == "" {
+			return fmt.Errorf("expect one graph, got %d", len(graphs))
+		}
+		return fmt.Errorf("graph %s not found", t.graphName)
+	}
+	return t.dst.Reload(g)
+}
+
+func selectGraph(graphs []*graph.Graph, name string) *graph.Graph {
+	if name == "" {
+		if len(graphs) != 1 {
+			return nil
+		}
+		return graphs[0]
+	}
+	for _, g := range graphs {
+		if g.GraphName == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func readWatched(path string) (os.FileInfo, []byte, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := os.ReadFile(path)
+	return st, b, err
+}
@@ -0,0 +1,91 @@
+package dag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calc.json")
+	write := func(content string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// 避免文件系统时间精度导致变化检测不到
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write(registryGraph, now)
+
+	d := New[int, int](WithRegistry(newCalcRegistry()))
+	errs := make(chan error, 10)
+	var reloaded int
+	r := NewReloader(time.Millisecond)
+	r.OnError = func(path string, err error) { errs <- err }
+	r.OnReload = func(path string, g *graph.Graph) { reloaded++ }
+	if err := r.Watch(path, "calc", d); err != nil {
+		t.Fatal(err)
+	}
+	// (1+1)*2+3
+	if v, err := d.RunSync(context.TODO(), 1); err != nil || v != 7 {
+		t.Fatal(v, err)
+	}
+
+	// 运行中的实例使用开始时的图
+	started, release := make(chan struct{}), make(chan struct{})
+	blocking := New[int, int]().SetGraph(newDiamondGraph())
+	blocking.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "a" {
+				close(started)
+				<-release
+			}
+			return len(s.CurrentNode.Name), nil
+		}
+	})
+	done := make(chan *RunResult[int])
+	go func() { done <- blocking.RunAsyncResult(context.TODO(), 0) }()
+	<-started
+	if err := blocking.Reload(graph.NewGraph(graph.WithNodes(&graph.Node{Name: "only"}))); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if res := <-done; len(res.Nodes) != 4 || res.Err != nil {
+		t.Fatal(res.Nodes, res.Err)
+	}
+
+	write(strings.Replace(registryGraph, `"n": 1}`, `"n": 10}`, 1), now.Add(time.Second))
+	r.Check()
+	if v, _ := d.RunSync(context.TODO(), 1); v != 25 || reloaded != 1 || len(errs) != 0 {
+		t.Fatal(v, reloaded, errs)
+	}
+
+	// 失败时保留上一个版本
+	write(strings.Replace(registryGraph, `"handler": "mul"`, `"handler": "pow"`, 1), now.Add(2*time.Second))
+	r.Check()
+	r.Check()
+	if v, _ := d.RunSync(context.TODO(), 1); v != 25 || len(errs) != 1 {
+		t.Fatal(v, len(errs))
+	}
+	if err := <-errs; !strings.Contains(err.Error(), "pow") {
+		t.Fatal(err)
+	}
+	write(`{"graphList": [`, now.Add(3*time.Second))
+	ctx, cancel := context.WithCancel(context.TODO())
+	go func() {
+		<-errs
+		cancel()
+	}()
+	r.Run(ctx)
+	if v, _ := d.RunSync(context.TODO(), 1); v != 25 {
+		t.Fatal(v)
+	}
+}
@@ -0,0 +1,157 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	ErrUnknownResource  = errors.New("unknown resource")
+	ErrResourceTooLarge = errors.New("resource request exceeds capacity")
+)
+
+// ResourcePool 命名资源, 限制所有运行对共享后端的并发占用
+type ResourcePool struct {
+	protect   sync.RWMutex
+	resources map[string]*resource
+}
+
+type resource struct {
+	sem   *semaphore.Weighted
+	units int64
+}
+
+// ResourceNeed 节点执行时需要占用的资源
+type ResourceNeed struct {
+	Name  string
+	Units int64
+}
+
+// GlobalResources 所有 Dag 共享的资源, Dag 上同名的资源优先
+var GlobalResources = NewResourcePool()
+
+func NewResourcePool() *ResourcePool {
+	return &ResourcePool{}
+}
+
+// DefineResource 在全局资源池中定义资源
+func DefineResource(name string, units int64) {
+	GlobalResources.Define(name, units)
+}
+
+// Define 定义或替换资源, 替换前已占用的资源仍归还给旧的容量
+func (p *ResourcePool) Define(name string, units int64) {
+	p.protect.Lock()
+	defer p.protect.Unlock()
+	if p.resources == nil {
+		p.resources = make(map[string]*resource)
+	}
+	p.resources[name] = &resource{sem: semaphore.NewWeighted(units), units: units}
+}
+
+func (p *ResourcePool) Remove(name string) {
+	p.protect.Lock()
+	defer p.protect.Unlock()
+	delete(p.resources, name)
+}
+
+// Capacity 返回资源的总量, 未定义时返回 false
+func (p *ResourcePool) Capacity(name string) (int64, bool) {
+	res := p.get(name)
+	if res == nil {
+		return 0, false
+	}
+	return res.units, true
+}
+
+func (p *ResourcePool) get(name string) *resource {
+	p.protect.RLock()
+	defer p.protect.RUnlock()
+	return p.resources[name]
+}
+
+// DefineResource 定义只在该 Dag 的运行之间共享的资源
+func (r *Dag[K, V]) DefineResource(name string, units int64) {
+	r.resources.Define(name, units)
+}
+
+// SetResources 设置节点执行前需要占用的资源, needs 为资源名到数量的映射
+func (r *Dag[K, V]) SetResources(nodeName string, needs map[string]int64) {
+	var list []ResourceNeed
+	for name, units := range needs {
+		if units > 0 {
+			list = append(list, ResourceNeed{Name: name, Units: units})
+		}
+	}
+	// 按名字顺序获取, 避免不同节点交叉等待导致死锁
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	nodeResources := make(map[string][]ResourceNeed)
+	w, _ := r.nodeResources.Load().(map[string][]ResourceNeed)
+	for k, v := range w {
+		nodeResources[k] = v
+	}
+	if len(list) == 0 {
+		delete(nodeResources, nodeName)
+	} else {
+		nodeResources[nodeName] = list
+	}
+	r.nodeResources.Store(nodeResources)
+}
+
+func (r *Dag[K, V]) getNodeResources() map[string][]ResourceNeed {
+	w, _ := r.nodeResources.Load().(map[string][]ResourceNeed)
+	return w
+}
+
+// acquireResources 依次获取资源, 失败时归还已获取的部分
+func acquireResources(ctx context.Context, pool *ResourcePool, needs []ResourceNeed) (func(), error) {
+	var held []func()
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i]()
+		}
+	}
+	for _, need := range needs {
+		res := pool.get(need.Name)
+		if res == nil {
+			res = GlobalResources.get(need.Name)
+		}
+		if res == nil {
+			release()
+			return nil, fmt.Errorf("%w: %s", ErrUnknownResource, need.Name)
+		}
+		if need.Units > res.units {
+			release()
+			return nil, fmt.Errorf("%w: %s needs %d of %d", ErrResourceTooLarge, need.Name, need.Units, res.units)
+		}
+		if err := res.sem.Acquire(ctx, need.Units); err != nil {
+			release()
+			return nil, err
+		}
+		sem, units := res.sem, need.Units
+		held = append(held, func() { sem.Release(units) })
+	}
+	return release, nil
+}
+
+func resourceHandler[K, V any](pool *ResourcePool, needs []ResourceNeed, handler HandlerFunc[K, V], queue *time.Duration) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		start := time.Now()
+		release, err := acquireResources(ctx, pool, needs)
+		*queue += time.Since(start)
+		if err != nil {
+			var e V
+			return e, err
+		}
+		defer release()
+		return handler(ctx, s)
+	}
+}
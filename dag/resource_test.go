@@ -0,0 +1,94 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResources(t *testing.T) {
+	var running, peak atomic.Int32
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" || nodeName == "c" {
+				units := int32(1)
+				if nodeName == "c" {
+					units = 2
+				}
+				n := running.Add(units)
+				defer running.Add(-units)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.DefineResource("db", 3)
+	d.SetResources("b", map[string]int64{"db": 1})
+	d.SetResources("c", map[string]int64{"db": 2})
+
+	var (
+		wg     sync.WaitGroup
+		queued atomic.Int32
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := d.RunAsyncResult(context.TODO(), 1)
+			if res.Err != nil || res.Output != 3 {
+				t.Error(res.Err)
+			}
+			if res.Nodes["a"].QueueTime != 0 {
+				t.Error(res.Nodes["a"].QueueTime)
+			}
+			if max(res.Nodes["b"].QueueTime, res.Nodes["c"].QueueTime) > 5*time.Millisecond {
+				queued.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 3 || queued.Load() == 0 {
+		t.Fatal(peak.Load(), queued.Load())
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		d.SetResources("b", map[string]int64{"db": 4})
+		if res := d.RunSyncResult(context.TODO(), 1); !errors.Is(res.Err, ErrResourceTooLarge) {
+			t.Fatal(res.Err)
+		}
+		d.SetResources("b", map[string]int64{"gpu": 1})
+		if res := d.RunSyncResult(context.TODO(), 1); !errors.Is(res.Err, ErrUnknownResource) {
+			t.Fatal(res.Err)
+		}
+		// Dag 上没有定义时使用全局资源
+		DefineResource("gpu", 1)
+		defer GlobalResources.Remove("gpu")
+		if res := d.RunSyncResult(context.TODO(), 1); res.Err != nil {
+			t.Fatal(res.Err)
+		}
+	})
+
+	t.Run("cancel while queued", func(t *testing.T) {
+		d.SetResources("b", map[string]int64{"gpu": 1})
+		DefineResource("gpu", 1)
+		defer GlobalResources.Remove("gpu")
+		sem := GlobalResources.get("gpu").sem
+		sem.Acquire(context.TODO(), 1)
+		defer sem.Release(1)
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+		if r := d.RunAsyncResult(ctx, 1); !errors.Is(r.Err, context.DeadlineExceeded) || r.Nodes["b"].QueueTime < 20*time.Millisecond {
+			t.Fatal(r.Err, r.Nodes["b"].QueueTime)
+		}
+	})
+}
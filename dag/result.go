@@ -0,0 +1,110 @@
+package dag
+
+import (
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// NodeReport 单个节点在一次运行中的结果
+type NodeReport struct {
+	Id       uint32
+	Name     string
+	Status   graph.NodeStatus
+	Order    uint32
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	Cache    CacheStatus
+	Shared   bool
+	Breaker  BreakerState
+	Fallback bool
+	// QueueTime 执行前等待限流和资源的时间
+	QueueTime time.Duration
+	// Compensation 运行失败后该节点执行的补偿
+	Compensation *CompensationReport
+}
+
+// RunResult 一次运行的输出以及每个节点的执行情况
+type RunResult[V any] struct {
+	RunID  string
+	Output V
+	Err    error
+	Nodes  map[string]*NodeReport
+	// Version, Fingerprint 经 VersionRegistry 运行时记录实际执行的版本
+	Version     int
+	Fingerprint string
+	// Compensated 运行失败后执行了补偿的节点, 按执行顺序
+	Compensated []string
+
+	graph *graph.Graph
+}
+
+// NodeRuns 转换为 graphview 可以叠加渲染的节点状态
+func (r *RunResult[V]) NodeRuns() map[string]graph.NodeRun {
+	ret := make(map[string]graph.NodeRun, len(r.Nodes))
+	for name, v := range r.Nodes {
+		run := graph.NodeRun{
+			Status:   v.Status,
+			Start:    v.Start,
+			Duration: v.Duration,
+		}
+		if v.Err != nil {
+			run.Error = v.Err.Error()
+		}
+		ret[name] = run
+	}
+	return ret
+}
+
+func (r *ExecuteState[K, V]) nodeReport(node *graph.Node) *NodeReport {
+	rep := &NodeReport{
+		Id:     node.Id,
+		Name:   node.Name,
+		Status: graph.StatusNotReached,
+	}
+	out, _ := r.NodeResult[node.Id].(*NodeOutput[V])
+	if out == nil {
+		if start, ok := r.running[node.Id]; ok {
+			rep.Status = graph.StatusRunning
+			rep.Start = start
+		}
+		return rep
+	}
+	rep.Order = out.Order
+	rep.Start = out.Start
+	rep.Duration = out.Duration
+	rep.Err = out.Err
+	rep.Cache = out.Cache
+	rep.Shared = out.Shared
+	rep.Breaker = out.Breaker
+	rep.Fallback = out.Fallback
+	rep.QueueTime = out.QueueTime
+	rep.Compensation = out.Compensation
+	switch {
+	case out.Err != nil:
+		rep.Status = graph.StatusFailed
+	case !out.Valid:
+		rep.Status = graph.StatusSkipped
+	default:
+		rep.Status = graph.StatusSucceeded
+	}
+	return rep
+}
+
+func (r *ExecuteState[K, V]) result(v V, err error) *RunResult[V] {
+	ret := &RunResult[V]{
+		RunID:  r.RunID,
+		Output: v,
+		Err:    err,
+		Nodes:  make(map[string]*NodeReport, len(r.G.NodeIdMapping)),
+		graph:  r.G,
+	}
+	r.read(func() {
+		for _, node := range r.G.NodeIdMapping {
+			ret.Nodes[node.Name] = r.nodeReport(node)
+		}
+		ret.Compensated = append([]string(nil), r.compensated...)
+	})
+	return ret
+}
@@ -0,0 +1,90 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+	"github.com/opengeektech/go-dag/graph/graphview"
+)
+
+func newDiamondGraph() *graph.Graph {
+	return graph.NewGraph(
+		graph.WithNodes(
+			&graph.Node{Name: "a"},
+			&graph.Node{Name: "b"},
+			&graph.Node{Name: "c"},
+			&graph.Node{Name: "d"},
+		),
+		graph.WithDependOn("b", "a"),
+		graph.WithDependOn("c", "a"),
+		graph.WithDependOn("d", "b", "c"),
+	)
+}
+
+func TestRunResult(t *testing.T) {
+	t.Run("diamond", func(t *testing.T) {
+		d := New[int, int]().SetGraph(newDiamondGraph())
+		d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+			return func(ctx context.Context, s *State[int, int]) (int, error) {
+				sum := s.Input
+				for _, v := range s.DependNodeResult {
+					sum += v
+				}
+				return sum, nil
+			}
+		})
+		for i := 0; i < 20; i++ {
+			for _, res := range []*RunResult[int]{
+				d.RunAsyncResult(context.TODO(), 1),
+				d.RunSyncResult(context.TODO(), 1),
+			} {
+				// a=1 b=2 c=2 d=1+2+2
+				if res.Err != nil || res.Output != 5 {
+					t.Fatal(res.Output, res.Err)
+				}
+				for name, v := range res.Nodes {
+					if v.Status != graph.StatusSucceeded {
+						t.Fatal(name, v.Status)
+					}
+				}
+			}
+		}
+	})
+	t.Run("failed", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		d := New[int, int]().SetGraph(newDiamondGraph())
+		d.SetFunc("a", func(ctx context.Context, s *State[int, int]) (int, error) {
+			return 1, nil
+		})
+		d.SetFunc("b", func(ctx context.Context, s *State[int, int]) (int, error) {
+			return 0, errBoom
+		})
+		d.SetFunc("d", func(ctx context.Context, s *State[int, int]) (int, error) {
+			return 1, nil
+		})
+		res := d.RunSyncResult(context.TODO(), 1)
+		if !errors.Is(res.Err, errBoom) {
+			t.Fatal(res.Err)
+		}
+		expect := map[string]graph.NodeStatus{
+			"a": graph.StatusSucceeded,
+			"b": graph.StatusFailed,
+			"d": graph.StatusNotReached,
+		}
+		for name, status := range expect {
+			if res.Nodes[name].Status != status {
+				t.Error(name, res.Nodes[name].Status)
+			}
+		}
+		if c := res.Nodes["c"].Status; c != graph.StatusSkipped && c != graph.StatusNotReached {
+			t.Error("c ", c)
+		}
+		s, err := graphview.GetMermaidGraphDesc(d.Graph(), graphview.WithRunStatus(res.NodeRuns()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log("\n" + s)
+	})
+}
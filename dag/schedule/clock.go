@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 调度器使用的时间来源, 测试中可以替换为 FakeClock
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// RealClock 使用系统时间
+var RealClock Clock = realClock{}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	_, ok := c.timers[t]
+	delete(c.timers, t)
+	if ok {
+		c.notify()
+	}
+	return ok
+}
+
+// FakeClock 只有调用 Advance/Set 时时间才会前进
+type FakeClock struct {
+	protect sync.Mutex
+	now     time.Time
+	timers  map[*fakeTimer]struct{}
+	changed chan struct{}
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, timers: make(map[*fakeTimer]struct{}), changed: make(chan struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers[t] = struct{}{}
+	c.notify()
+	return t
+}
+
+// notify 唤醒 BlockUntil, 调用方持有锁
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Advance 前进 d 并触发到期的 Timer
+func (c *FakeClock) Advance(d time.Duration) {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.at.After(c.now) {
+			t.ch <- c.now
+			delete(c.timers, t)
+		}
+	}
+	c.notify()
+}
+
+// Timers 返回尚未触发也未停止的 Timer 数
+func (c *FakeClock) Timers() int {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil 阻塞直到至少有 n 个 Timer 在等待, 用于确认调度器已进入等待
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.protect.Lock()
+		count, changed := len(c.timers), c.changed
+		c.protect.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
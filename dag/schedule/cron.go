@@ -0,0 +1,219 @@
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trigger 计算 after 之后的下一次触发时间, 返回零值表示不再触发
+type Trigger interface {
+	Next(after time.Time) time.Time
+}
+
+// Interval 固定间隔触发
+type Interval time.Duration
+
+func Every(d time.Duration) Interval {
+	return Interval(d)
+}
+
+func (r Interval) Next(after time.Time) time.Time {
+	if r <= 0 {
+		return time.Time{}
+	}
+	return after.Add(time.Duration(r))
+}
+
+// Cron 标准 5 段 cron 表达式: 分 时 日 月 周
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// 日和周都有限制时满足其一即可, 与 crontab 一致
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronFields = [5]cronField{
+		{min: 0, max: 59},
+		{min: 0, max: 23},
+		{min: 1, max: 31},
+		{min: 1, max: 12, names: map[string]int{
+			"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+			"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+		}},
+		// 7 与 0 都表示周日
+		{min: 0, max: 7, names: map[string]int{
+			"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+		}},
+	}
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// Parse 解析 cron 表达式, 宏(@daily 等)或 "@every 5m"
+func Parse(spec string) (Trigger, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("cron %q: interval must be positive", spec)
+		}
+		return Every(d), nil
+	}
+	return ParseCron(spec)
+}
+
+// ParseCron 解析 5 段 cron 表达式, 使用本地时区
+func ParseCron(spec string) (*Cron, error) {
+	expr := strings.TrimSpace(spec)
+	if v, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = v
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: expect 5 fields, got %d", spec, len(parts))
+	}
+	c := &Cron{loc: time.Local}
+	sets := [5]*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, part := range parts {
+		v, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: field %d: %w", spec, i+1, err)
+		}
+		*sets[i] = v
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domStar = parts[2] == "*" || parts[2] == "?"
+	c.dowStar = parts[4] == "*" || parts[4] == "?"
+	return c, nil
+}
+
+// In 返回在 loc 时区计算的副本
+func (c *Cron) In(loc *time.Location) *Cron {
+	ret := *c
+	ret.loc = loc
+	return &ret
+}
+
+func parseCronField(s string, f cronField) (uint64, error) {
+	var ret uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+			rng, step = item[:i], n
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" 表示从 5 开始每 15 个
+			if step == 1 {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			ret |= 1 << v
+		}
+	}
+	return ret, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *Cron) dayMatch(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next 返回 after 之后(不含)第一个匹配的整分钟, 5 年内没有匹配时返回零值
+func (c *Cron) Next(after time.Time) time.Time {
+	loc := c.loc
+	if loc == nil {
+		loc = time.Local
+	}
+	t := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if c.month&(1<<t.Month()) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatch(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			// 跳到本小时内下一个匹配的分钟
+			rest := c.minute >> t.Minute()
+			if rest == 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			} else {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
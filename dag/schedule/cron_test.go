@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		spec  string
+		after string
+		want  string
+	}{
+		{"*/15 * * * *", "2024-06-01 10:07", "2024-06-01 10:15"},
+		{"*/15 * * * *", "2024-06-01 10:45", "2024-06-01 11:00"},
+		{"5/20 * * * *", "2024-06-01 10:06", "2024-06-01 10:25"},
+		{"0 9 * * mon-fri", "2024-06-01 10:00", "2024-06-03 09:00"},
+		{"0 0 1,15 * *", "2024-01-15 00:00", "2024-02-01 00:00"},
+		{"0 0 * * 7", "2024-06-03 00:00", "2024-06-09 00:00"},
+		// 日和周都有限制时满足其一即可
+		{"0 0 13 * fri", "2024-09-01 00:00", "2024-09-06 00:00"},
+		{"0 0 29 feb *", "2025-01-01 00:00", "2028-02-29 00:00"},
+		{"@hourly", "2024-12-31 23:59", "2025-01-01 00:00"},
+		{"@daily", "2024-06-01 00:00", "2024-06-02 00:00"},
+		{"0 0 30 2 *", "2024-01-01 00:00", ""},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.spec)
+		if err != nil {
+			t.Fatal(c.spec, err)
+		}
+		got := cron.In(time.UTC).Next(at(c.after))
+		if c.want == "" {
+			if !got.IsZero() {
+				t.Fatal(c.spec, got)
+			}
+			continue
+		}
+		if !got.Equal(at(c.want)) {
+			t.Fatal(c.spec, c.after, got)
+		}
+	}
+	for _, spec := range []string{"61 * * * *", "* * *", "a * * * *", "5-1 * * * *", "*/0 * * * *", "@every -1s"} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatal(spec)
+		}
+	}
+	if tr, err := Parse("@every 90s"); err != nil || tr.Next(at("2024-06-01 10:00")) != at("2024-06-01 10:01").Add(30*time.Second) {
+		t.Fatal(tr, err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package schedule 按 cron 表达式或固定间隔运行 dag
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opengeektech/go-dag/dag"
+)
+
+// OverlapPolicy 上一次运行尚未结束时再次触发的处理方式
+type OverlapPolicy uint8
+
+const (
+	// OverlapSkip 跳过本次触发, 通过错误回调报告 ErrSkipped
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue 排队, 上一次结束后依次运行
+	OverlapQueue
+	// OverlapAllow 并发运行
+	OverlapAllow
+)
+
+var (
+	ErrJobExists = errors.New("job already exists")
+	ErrSkipped   = errors.New("previous run still active, skipped")
+	ErrRunning   = errors.New("scheduler already running")
+	ErrStopping  = errors.New("scheduler is stopping")
+)
+
+type RunFunc func(ctx context.Context, at time.Time) error
+
+// InputFunc 为每次触发生成 dag 的输入, at 为计划触发时间
+type InputFunc[K any] func(at time.Time) (K, error)
+
+type Job struct {
+	Name    string
+	Trigger Trigger
+	Run     RunFunc
+	Overlap OverlapPolicy
+	// CatchUp 错过的触发最多补跑几次, 0 表示只运行最近的一次. 补跑按时间顺序依次执行
+	CatchUp int
+	// Jitter 每次触发随机延迟 [0, Jitter), 传给 Run 的仍是计划时间
+	Jitter time.Duration
+	// LastRun 上一次运行的计划时间, 通常来自持久化; 为零时从启动时开始计算
+	LastRun time.Time
+}
+
+type jobState struct {
+	Job
+	cancel  context.CancelFunc
+	removed bool
+	running int
+	queue   []time.Time
+}
+
+type Option func(*Scheduler)
+
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithErrorHandler 运行失败或因重叠被跳过时调用
+func WithErrorHandler(fn func(job string, at time.Time, err error)) Option {
+	return func(s *Scheduler) {
+		s.onError = fn
+	}
+}
+
+type Scheduler struct {
+	clock   Clock
+	onError func(job string, at time.Time, err error)
+
+	protect sync.Mutex
+	jobs    map[string]*jobState
+	ctx     context.Context
+	// stopping Run 的 ctx 已结束, 正在等待运行结束
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{clock: RealClock, jobs: make(map[string]*jobState)}
+	for _, fn := range opts {
+		fn(s)
+	}
+	return s
+}
+
+// Add 添加任务, 调度器运行中添加的任务立即开始计时. 调度器停止过程中返回 ErrStopping
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Trigger == nil || job.Run == nil {
+		return fmt.Errorf("job %q: name, trigger and run are required", job.Name)
+	}
+	s.protect.Lock()
+	defer s.protect.Unlock()
+	if s.stopping {
+		return fmt.Errorf("%w: %s", ErrStopping, job.Name)
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+	j := &jobState{Job: job}
+	s.jobs[job.Name] = j
+	if s.ctx != nil {
+		s.start(j)
+	}
+	return nil
+}
+
+// Remove 移除任务, 已经开始的运行不受影响, 排队的触发被丢弃
+func (s *Scheduler) Remove(name string) bool {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return false
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.removed = true
+	j.queue = nil
+	delete(s.jobs, name)
+	return true
+}
+
+func (s *Scheduler) Jobs() []string {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+	ret := make([]string, 0, len(s.jobs))
+	for k := range s.jobs {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Run 开始调度直到 ctx 结束, 返回前等待所有运行结束
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.protect.Lock()
+	if s.ctx != nil {
+		s.protect.Unlock()
+		return ErrRunning
+	}
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+	s.protect.Unlock()
+	<-ctx.Done()
+	// 等待期间不再启动新的任务循环
+	s.protect.Lock()
+	s.stopping = true
+	s.protect.Unlock()
+	s.wg.Wait()
+	s.protect.Lock()
+	s.ctx = nil
+	s.stopping = false
+	s.protect.Unlock()
+	return ctx.Err()
+}
+
+// start 调用方持有锁. 任务循环使用可以单独取消的 ctx, 每次运行使用调度器的 ctx,
+// 移除任务只停止后续触发
+func (s *Scheduler) start(j *jobState) {
+	root := s.ctx
+	ctx, cancel := context.WithCancel(root)
+	j.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx, root, j)
+	}()
+}
+
+func (s *Scheduler) loop(ctx, runCtx context.Context, j *jobState) {
+	last := j.LastRun
+	if last.IsZero() {
+		last = s.clock.Now()
+	}
+	for {
+		next := j.Trigger.Next(last)
+		if next.IsZero() {
+			return
+		}
+		wait := next.Sub(s.clock.Now())
+		if j.Jitter > 0 {
+			wait += rand.N(j.Jitter)
+		}
+		t := s.clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		}
+		due := dueTimes(j.Trigger, last, s.clock.Now(), j.CatchUp)
+		if len(due) == 0 {
+			continue
+		}
+		policy := j.Overlap
+		if len(due) > 1 {
+			policy = OverlapQueue
+		}
+		for _, at := range due {
+			s.fire(runCtx, j, at, policy)
+		}
+		last = due[len(due)-1]
+	}
+}
+
+// dueTimes 返回 (last, now] 内的触发时间, 最多保留最近的 max(catchUp, 1) 个.
+// 固定间隔直接计算, 不逐个遍历错过的触发
+func dueTimes(trigger Trigger, last, now time.Time, catchUp int) []time.Time {
+	n := max(catchUp, 1)
+	if every, ok := trigger.(Interval); ok {
+		if every <= 0 || !now.After(last) {
+			return nil
+		}
+		count := int64(now.Sub(last) / time.Duration(every))
+		var ret []time.Time
+		for k := max(count-int64(n)+1, 1); k <= count; k++ {
+			ret = append(ret, last.Add(time.Duration(k)*time.Duration(every)))
+		}
+		return ret
+	}
+	var ret []time.Time
+	for t := trigger.Next(last); !t.IsZero() && !t.After(now); t = trigger.Next(t) {
+		ret = append(ret, t)
+		if len(ret) > n {
+			ret = ret[1:]
+		}
+	}
+	return ret
+}
+
+func (s *Scheduler) fire(ctx context.Context, j *jobState, at time.Time, policy OverlapPolicy) {
+	s.protect.Lock()
+	if j.removed {
+		s.protect.Unlock()
+		return
+	}
+	if j.running > 0 {
+		switch policy {
+		case OverlapSkip:
+			s.protect.Unlock()
+			s.report(j.Name, at, ErrSkipped)
+			return
+		case OverlapQueue:
+			j.queue = append(j.queue, at)
+			s.protect.Unlock()
+			return
+		}
+	}
+	j.running++
+	s.wg.Add(1)
+	s.protect.Unlock()
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, j, at)
+	}()
+}
+
+// execute 运行一次, 结束后继续运行排队的触发
+func (s *Scheduler) execute(ctx context.Context, j *jobState, at time.Time) {
+	for {
+		if err := s.call(ctx, j, at); err != nil {
+			s.report(j.Name, at, err)
+		}
+		s.protect.Lock()
+		if len(j.queue) > 0 && ctx.Err() == nil {
+			at = j.queue[0]
+			j.queue = j.queue[1:]
+			s.protect.Unlock()
+			continue
+		}
+		j.queue = nil
+		j.running--
+		s.protect.Unlock()
+		return
+	}
+}
+
+func (s *Scheduler) call(ctx context.Context, j *jobState, at time.Time) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic error: %v", v)
+		}
+	}()
+	return j.Run(ctx, at)
+}
+
+func (s *Scheduler) report(job string, at time.Time, err error) {
+	if s.onError != nil {
+		s.onError(job, at, err)
+	}
+}
+
+// DagRun 每次触发时从 ns 中按名字查找 dag 并运行, 重新注册后的 dag 在下一次触发生效.
+// input 为 nil 时使用 K 的零值
+func DagRun[K, V any](ns *dag.Namespace, name string, input InputFunc[K]) RunFunc {
+	return func(ctx context.Context, at time.Time) error {
+		d, err := dag.Lookup[K, V](ns, name)
+		if err != nil {
+			return err
+		}
+		var k K
+		if input != nil {
+			if k, err = input(at); err != nil {
+				return err
+			}
+		}
+		_, err = d.RunAsync(ctx, k)
+		return err
+	}
+}
+
+// GlobalDagRun 运行默认注册表中的 dag
+func GlobalDagRun[K, V any](name string, input InputFunc[K]) RunFunc {
+	return DagRun[K, V](dag.DefaultNamespace, name, input)
+}
@@ -0,0 +1,259 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/dag"
+	"github.com/opengeektech/go-dag/graph"
+)
+
+type schedulerTest struct {
+	clock  *FakeClock
+	s      *Scheduler
+	errs   chan error
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func newSchedulerTest(t *testing.T, jobs ...Job) *schedulerTest {
+	st := &schedulerTest{
+		clock: NewFakeClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)),
+		errs:  make(chan error, 10),
+		done:  make(chan error),
+	}
+	st.s = New(WithClock(st.clock), WithErrorHandler(func(job string, at time.Time, err error) {
+		st.errs <- err
+	}))
+	for _, j := range jobs {
+		if err := st.s.Add(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	st.cancel = cancel
+	go func() { st.done <- st.s.Run(ctx) }()
+	t.Cleanup(func() {
+		st.cancel()
+		<-st.done
+	})
+	return st
+}
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("interval", func(t *testing.T) {
+		ats := make(chan time.Time, 10)
+		st := newSchedulerTest(t, Job{Name: "tick", Trigger: Every(time.Minute), Run: func(ctx context.Context, at time.Time) error {
+			ats <- at
+			return nil
+		}})
+		if err := st.s.Add(Job{Name: "tick", Trigger: Every(time.Minute), Run: func(context.Context, time.Time) error { return nil }}); !errors.Is(err, ErrJobExists) {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 3; i++ {
+			st.clock.BlockUntil(1)
+			st.clock.Advance(time.Minute)
+			if at := <-ats; !at.Equal(start.Add(time.Duration(i) * time.Minute)) {
+				t.Fatal(i, at)
+			}
+		}
+		if !st.s.Remove("tick") || st.s.Remove("tick") {
+			t.Fatal("remove")
+		}
+	})
+	for _, c := range []struct {
+		name   string
+		policy OverlapPolicy
+	}{{"skip", OverlapSkip}, {"queue", OverlapQueue}} {
+		t.Run(c.name, func(t *testing.T) {
+			ats := make(chan time.Time, 10)
+			release := make(chan struct{})
+			st := newSchedulerTest(t, Job{Name: "slow", Trigger: Every(time.Minute), Overlap: c.policy, Run: func(ctx context.Context, at time.Time) error {
+				ats <- at
+				<-release
+				return nil
+			}})
+			st.clock.BlockUntil(1)
+			st.clock.Advance(time.Minute)
+			<-ats
+			st.clock.BlockUntil(1)
+			st.clock.Advance(time.Minute)
+			if c.policy == OverlapSkip {
+				if err := <-st.errs; !errors.Is(err, ErrSkipped) {
+					t.Fatal(err)
+				}
+				close(release)
+				return
+			}
+			st.clock.BlockUntil(1)
+			close(release)
+			if at := <-ats; !at.Equal(start.Add(2 * time.Minute)) {
+				t.Fatal(at)
+			}
+		})
+	}
+	t.Run("remove while running", func(t *testing.T) {
+		ats := make(chan time.Time, 10)
+		release := make(chan struct{})
+		done := make(chan error, 1)
+		st := newSchedulerTest(t, Job{Name: "slow", Trigger: Every(time.Minute), Overlap: OverlapQueue, Run: func(ctx context.Context, at time.Time) error {
+			ats <- at
+			select {
+			case <-release:
+				done <- nil
+			case <-ctx.Done():
+				done <- ctx.Err()
+			}
+			return nil
+		}})
+		st.clock.BlockUntil(1)
+		st.clock.Advance(time.Minute)
+		<-ats
+		// 排队的触发在移除后丢弃, 已经开始的运行正常结束
+		st.clock.BlockUntil(1)
+		st.clock.Advance(time.Minute)
+		st.clock.BlockUntil(1)
+		if !st.s.Remove("slow") {
+			t.Fatal("remove")
+		}
+		close(release)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case at := <-ats:
+			t.Fatal(at)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+	t.Run("catch up", func(t *testing.T) {
+		ats := make(chan time.Time, 10)
+		newSchedulerTest(t, Job{
+			Name:    "missed",
+			Trigger: Every(time.Minute),
+			CatchUp: 3,
+			LastRun: start.Add(-10 * time.Minute),
+			Run: func(ctx context.Context, at time.Time) error {
+				ats <- at
+				return nil
+			},
+		})
+		for i := 2; i >= 0; i-- {
+			if at := <-ats; !at.Equal(start.Add(time.Duration(-i) * time.Minute)) {
+				t.Fatal(i, at)
+			}
+		}
+	})
+	t.Run("dag", func(t *testing.T) {
+		ns := dag.NewNamespace("schedule")
+		outputs := make(chan time.Time, 10)
+		d := dag.New[time.Time, time.Time]().SetGraph(graph.NewGraph(graph.WithNodes(&graph.Node{Name: "report"})))
+		d.SetFunc("report", func(ctx context.Context, s *dag.State[time.Time, time.Time]) (time.Time, error) {
+			outputs <- s.Input
+			return s.Input, nil
+		})
+		dag.Register(ns, "nightly", d)
+		cron, _ := ParseCron("0 2 * * *")
+		st := newSchedulerTest(t,
+			Job{Name: "nightly", Trigger: cron.In(time.UTC), Run: DagRun[time.Time, time.Time](ns, "nightly", func(at time.Time) (time.Time, error) {
+				return at.AddDate(0, 0, -1), nil
+			})},
+			Job{Name: "mismatch", Trigger: cron.In(time.UTC), Run: DagRun[int, int](ns, "nightly", nil)},
+		)
+		st.clock.BlockUntil(2)
+		st.clock.Advance(2 * time.Hour)
+		if v := <-outputs; !v.Equal(start.Add(2*time.Hour).AddDate(0, 0, -1)) {
+			t.Fatal(v)
+		}
+		if err := <-st.errs; !errors.Is(err, dag.ErrDagTypeMismatch) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestJitter(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	s := New(WithClock(clock))
+	ats := make(chan time.Time, 1)
+	s.Add(Job{Name: "j", Trigger: Every(time.Minute), Jitter: time.Second, Run: func(ctx context.Context, at time.Time) error {
+		ats <- clock.Now()
+		return nil
+	}})
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute + time.Second)
+	if at := <-ats; !at.Equal(time.Date(2024, 6, 1, 0, 1, 1, 0, time.UTC)) {
+		t.Fatal(at)
+	}
+	cancel()
+	<-done
+}
+
+func TestDueTimes(t *testing.T) {
+	last := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	every, _ := ParseCron("* * * * *")
+	for _, span := range []time.Duration{30 * time.Second, time.Minute, 150 * time.Second, time.Hour} {
+		for _, catchUp := range []int{0, 2, 100} {
+			want := dueTimes(every.In(time.UTC), last, last.Add(span), catchUp)
+			got := dueTimes(Every(time.Minute), last, last.Add(span), catchUp)
+			if len(got) != len(want) {
+				t.Fatal(span, catchUp, got, want)
+			}
+			for i := range got {
+				if !got[i].Equal(want[i]) {
+					t.Fatal(span, catchUp, got, want)
+				}
+			}
+		}
+	}
+	// 长时间停机后只计算最近的几次
+	now := last.AddDate(100, 0, 0)
+	got := dueTimes(Every(time.Second), last, now, 2)
+	if len(got) != 2 || !got[1].Equal(now) || !got[0].Equal(now.Add(-time.Second)) {
+		t.Fatal(got)
+	}
+}
+
+func TestAddWhileStopping(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	s := New(WithClock(clock))
+	started, release := make(chan struct{}), make(chan struct{})
+	s.Add(Job{Name: "slow", Trigger: Every(time.Minute), Run: func(ctx context.Context, at time.Time) error {
+		close(started)
+		<-release
+		return nil
+	}})
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	cancel()
+	job := Job{Name: "late", Trigger: Every(time.Minute), Run: func(context.Context, time.Time) error { return nil }}
+	for {
+		err := s.Add(job)
+		if errors.Is(err, ErrStopping) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Run 还没有进入停止阶段
+		s.Remove(job.Name)
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+}
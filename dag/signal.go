@@ -0,0 +1,147 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotSignalNode   = errors.New("node does not wait for signal")
+	ErrSignalDelivered = errors.New("signal already delivered")
+	ErrSignalTimeout   = errors.New("signal timeout")
+	ErrSignalClosed    = errors.New("signal no longer accepted")
+)
+
+const (
+	signalOpen int32 = iota
+	signalDelivered
+	signalClosed
+)
+
+// SignalConfig 等待外部信号的节点配置
+type SignalConfig struct {
+	// Timeout 为 0 时一直等待直到运行被取消
+	Timeout time.Duration
+	// Default 超时后作为信号内容继续执行, 为 nil 时节点以 ErrSignalTimeout 失败
+	Default any
+}
+
+type signalBox struct {
+	cfg     SignalConfig
+	ch      chan any
+	waiting atomic.Bool
+	// state 只能从 signalOpen 变为送达或关闭一次
+	state atomic.Int32
+}
+
+// SetSignal 让节点在执行前等待 Dag.Signal 送达的数据, 数据通过 State.Signal 传给处理函数.
+// 节点没有处理函数时输出上游结果
+func (r *Dag[K, V]) SetSignal(nodeName string, cfg SignalConfig) {
+	signals := make(map[string]SignalConfig)
+	w, _ := r.signals.Load().(map[string]SignalConfig)
+	for k, v := range w {
+		signals[k] = v
+	}
+	signals[nodeName] = cfg
+	r.signals.Store(signals)
+}
+
+func (r *Dag[K, V]) newSignalBoxes() map[string]*signalBox {
+	w, _ := r.signals.Load().(map[string]SignalConfig)
+	if len(w) == 0 {
+		return nil
+	}
+	ret := make(map[string]*signalBox, len(w))
+	for k, v := range w {
+		ret[k] = &signalBox{cfg: v, ch: make(chan any, 1)}
+	}
+	return ret
+}
+
+// Signal 向运行 runID 中的节点送达数据. 节点尚未开始等待时数据会被保存, 每个节点只接收一次,
+// 之后的调用返回 ErrSignalDelivered, 节点已经超时或取消时返回 ErrSignalClosed
+func (r *Dag[K, V]) Signal(runID, nodeName string, payload any) error {
+	v, ok := r.states.Load(runID)
+	if !ok {
+		return ErrRunNotFound
+	}
+	box := v.(*ExecuteState[K, V]).signals[nodeName]
+	if box == nil {
+		return ErrNotSignalNode
+	}
+	if !box.state.CompareAndSwap(signalOpen, signalDelivered) {
+		if box.state.Load() == signalDelivered {
+			return ErrSignalDelivered
+		}
+		return ErrSignalClosed
+	}
+	box.ch <- payload
+	return nil
+}
+
+func (b *signalBox) wait(ctx context.Context) (any, error) {
+	b.waiting.Store(true)
+	defer b.waiting.Store(false)
+	var timeout <-chan time.Time
+	if b.cfg.Timeout > 0 {
+		t := time.NewTimer(b.cfg.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case v := <-b.ch:
+		return v, nil
+	case <-ctx.Done():
+		if v, ok := b.close(); ok {
+			return v, nil
+		}
+		return nil, ctx.Err()
+	case <-timeout:
+		if v, ok := b.close(); ok {
+			return v, nil
+		}
+		if b.cfg.Default == nil {
+			return nil, ErrSignalTimeout
+		}
+		return b.cfg.Default, nil
+	}
+}
+
+// close 停止接收信号, 与 Signal 同时发生且信号先送达时返回该信号
+func (b *signalBox) close() (any, bool) {
+	if b.state.CompareAndSwap(signalOpen, signalClosed) {
+		return nil, false
+	}
+	return <-b.ch, true
+}
+
+// signalHandler 在 handler 之前等待信号
+func signalHandler[K, V any](box *signalBox, handler HandlerFunc[K, V]) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		payload, err := box.wait(ctx)
+		if err != nil {
+			var e V
+			return e, err
+		}
+		s.Signal = payload
+		if handler == nil {
+			return s.Last, nil
+		}
+		return handler(ctx, s)
+	}
+}
+
+// waitingSignals 返回正在等待信号的节点
+func (r *ExecuteState[K, V]) waitingSignals() []string {
+	var ret []string
+	for name, box := range r.signals {
+		if box.waiting.Load() {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
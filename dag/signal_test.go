@@ -0,0 +1,104 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func TestSignal(t *testing.T) {
+	newApproval := func(cfg SignalConfig) *Dag[int, string] {
+		d := New[int, string]().SetGraph(graph.NewGraph(
+			graph.WithNodes(&graph.Node{Name: "request"}, &graph.Node{Name: "approve"}, &graph.Node{Name: "apply"}),
+			graph.WithDependOn("approve", "request"),
+			graph.WithDependOn("apply", "approve"),
+		))
+		d.SetFunc("request", func(ctx context.Context, s *State[int, string]) (string, error) {
+			if s.RunID == "" {
+				return "", errors.New("no run id")
+			}
+			return "requested", nil
+		})
+		d.SetFunc("approve", func(ctx context.Context, s *State[int, string]) (string, error) {
+			return s.Signal.(string), nil
+		})
+		d.SetFunc("apply", func(ctx context.Context, s *State[int, string]) (string, error) {
+			return s.Last + " applied", nil
+		})
+		d.SetSignal("approve", cfg)
+		return d
+	}
+
+	t.Run("deliver", func(t *testing.T) {
+		d := newApproval(SignalConfig{})
+		h := d.Submit(context.TODO(), 1)
+		waitStatus(t, h, func(st RunStatus) bool { return len(st.Waiting) == 1 && st.Waiting[0] == "approve" })
+		if err := d.Signal(h.ID(), "request", "x"); !errors.Is(err, ErrNotSignalNode) {
+			t.Fatal(err)
+		}
+		if err := d.Signal(h.ID(), "approve", "ok"); err != nil {
+			t.Fatal(err)
+		}
+		res := h.Wait()
+		if res.Err != nil || res.Output != "ok applied" || res.RunID != h.ID() {
+			t.Fatal(res.Output, res.Err)
+		}
+		if err := d.Signal(h.ID(), "approve", "again"); !errors.Is(err, ErrRunNotFound) {
+			t.Fatal(err)
+		}
+	})
+	t.Run("once", func(t *testing.T) {
+		d := newApproval(SignalConfig{})
+		h := d.Submit(context.TODO(), 1, WithBreakpoint("apply"))
+		if err := d.Signal(h.ID(), "approve", "ok"); err != nil {
+			t.Fatal(err)
+		}
+		waitStatus(t, h, func(st RunStatus) bool { return st.Paused })
+		// 节点已经处理完第一次的数据
+		for i := 0; i < 2; i++ {
+			if err := d.Signal(h.ID(), "approve", "again"); !errors.Is(err, ErrSignalDelivered) {
+				t.Fatal(i, err)
+			}
+		}
+		h.Resume()
+		if res := h.Wait(); res.Err != nil || res.Output != "ok applied" {
+			t.Fatal(res.Output, res.Err)
+		}
+
+		d = newApproval(SignalConfig{Timeout: time.Millisecond, Default: "auto"})
+		h = d.Submit(context.TODO(), 1, WithBreakpoint("apply"))
+		waitStatus(t, h, func(st RunStatus) bool { return st.Paused })
+		if err := d.Signal(h.ID(), "approve", "late"); !errors.Is(err, ErrSignalClosed) {
+			t.Fatal(err)
+		}
+		h.Resume()
+		if res := h.Wait(); res.Err != nil || res.Output != "auto applied" {
+			t.Fatal(res.Output, res.Err)
+		}
+	})
+	t.Run("default", func(t *testing.T) {
+		d := newApproval(SignalConfig{Timeout: time.Millisecond, Default: "auto"})
+		if v, err := d.RunAsync(context.TODO(), 1); err != nil || v != "auto applied" {
+			t.Fatal(v, err)
+		}
+	})
+	t.Run("timeout", func(t *testing.T) {
+		d := newApproval(SignalConfig{Timeout: time.Millisecond})
+		res := d.RunSyncResult(context.TODO(), 1)
+		if !errors.Is(res.Err, ErrSignalTimeout) || res.Nodes["approve"].Status != graph.StatusFailed {
+			t.Fatal(res.Err)
+		}
+	})
+	t.Run("cancel", func(t *testing.T) {
+		d := newApproval(SignalConfig{})
+		h := d.Submit(context.TODO(), 1)
+		waitStatus(t, h, func(st RunStatus) bool { return len(st.Waiting) == 1 })
+		h.Cancel(nil)
+		if res := h.Wait(); !errors.Is(res.Err, context.Canceled) {
+			t.Fatal(res.Err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package dag
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// SetSingleflight 合并相同键的并发运行: 同一时刻键相同的 RunSync/RunAsync 只执行一次并共享结果.
+// key 返回空字符串时不合并. 调用方取消时自己立即返回, 共享的执行继续, 直到完成或第一个调用方的截止时间
+func (r *Dag[K, V]) SetSingleflight(key func(k K) string) {
+	r.runKey.Store(key)
+}
+
+// SetNodeSingleflight 合并不同运行中同一节点的并发执行, key 为 nil 时使用 DefaultCacheKey
+func (r *Dag[K, V]) SetNodeSingleflight(nodeName string, key func(s *State[K, V]) (string, error)) {
+	if key == nil {
+		key = DefaultCacheKey[K, V]
+	}
+	flights := make(map[string]func(s *State[K, V]) (string, error))
+	w, _ := r.nodeFlights.Load().(map[string]func(s *State[K, V]) (string, error))
+	for k, v := range w {
+		flights[k] = v
+	}
+	flights[nodeName] = key
+	r.nodeFlights.Store(flights)
+}
+
+func (r *Dag[K, V]) getNodeFlights() map[string]func(s *State[K, V]) (string, error) {
+	w, _ := r.nodeFlights.Load().(map[string]func(s *State[K, V]) (string, error))
+	return w
+}
+
+type flightResult[V any] struct {
+	v   V
+	err error
+}
+
+// coalesce 按 SetSingleflight 的键合并 fn 的并发调用
+func (r *Dag[K, V]) coalesce(ctx context.Context, k K, fn func(ctx context.Context) (V, error)) (V, error) {
+	keyFn, _ := r.runKey.Load().(func(k K) string)
+	if keyFn == nil {
+		return fn(ctx)
+	}
+	key := keyFn(k)
+	if key == "" {
+		return fn(ctx)
+	}
+	ch := r.runGroup.DoChan(key, func() (any, error) {
+		fctx, cancel := detach(ctx)
+		defer cancel()
+		v, err := fn(fctx)
+		// 错误放在结果中返回, 与 fn 的返回值保持一致
+		return flightResult[V]{v, err}, nil
+	})
+	select {
+	case ret := <-ch:
+		res := ret.Val.(flightResult[V])
+		return res.v, res.err
+	case <-ctx.Done():
+		var e V
+		return e, context.Cause(ctx)
+	}
+}
+
+// detach 返回不随 ctx 取消但保留其截止时间的 ctx, 用于多个调用方共享的执行
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	fctx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(fctx, deadline)
+	}
+	return fctx, func() {}
+}
+
+// flightHandler 共享的执行不受发起方 ctx 取消的影响, 只保留其截止时间,
+// 避免发起方的运行失败导致其他等待的运行收到 context.Canceled
+func flightHandler[K, V any](group *singleflight.Group, keyFn func(s *State[K, V]) (string, error), handler HandlerFunc[K, V], shared *bool) HandlerFunc[K, V] {
+	return func(ctx context.Context, s *State[K, V]) (V, error) {
+		key, err := keyFn(s)
+		if err != nil || key == "" {
+			return handler(ctx, s)
+		}
+		executed := false
+		ret, _, _ := group.Do(s.CurrentNode.Name+":"+key, func() (any, error) {
+			executed = true
+			fctx, cancel := detach(ctx)
+			defer cancel()
+			v, err := handler(fctx, s)
+			return flightResult[V]{v, err}, nil
+		})
+		*shared = !executed
+		res := ret.(flightResult[V])
+		return res.v, res.err
+	}
+}
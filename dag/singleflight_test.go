@@ -0,0 +1,180 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" {
+				calls.Add(1)
+				<-release
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	concurrent := func(n int, run func(i int)) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+		// 等待所有调用方进入合并
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	}
+
+	t.Run("run", func(t *testing.T) {
+		d.SetSingleflight(func(k int) string {
+			if k < 0 {
+				return ""
+			}
+			return "same"
+		})
+		defer d.SetSingleflight(nil)
+		outputs := make([]int, 5)
+		concurrent(5, func(i int) {
+			if i%2 == 0 {
+				outputs[i], _ = d.RunSync(context.TODO(), 1)
+			} else {
+				outputs[i], _ = d.RunAsync(context.TODO(), 1)
+			}
+		})
+		if calls.Load() != 1 {
+			t.Fatal(calls.Load())
+		}
+		for _, v := range outputs {
+			if v != 3 {
+				t.Fatal(outputs)
+			}
+		}
+	})
+	t.Run("node", func(t *testing.T) {
+		calls.Store(0)
+		release = make(chan struct{})
+		d.SetNodeSingleflight("b", func(s *State[int, int]) (string, error) {
+			return "lookup", nil
+		})
+		results := make([]*RunResult[int], 2)
+		concurrent(2, func(i int) {
+			results[i] = d.RunAsyncResult(context.TODO(), i)
+		})
+		if calls.Load() != 1 {
+			t.Fatal(calls.Load())
+		}
+		shared := 0
+		for i, res := range results {
+			if res.Err != nil || res.Nodes["c"].Shared || res.Output != i+2 {
+				t.Fatal(i, res.Output, res.Err)
+			}
+			if res.Nodes["b"].Shared {
+				shared++
+			}
+		}
+		// 只有没有执行的一方记为共享
+		if shared != 1 {
+			t.Fatal(shared)
+		}
+	})
+}
+
+func TestSingleflight_LeaderFailed(t *testing.T) {
+	var calls atomic.Int32
+	release, failC := make(chan struct{}), make(chan struct{})
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			switch {
+			case nodeName == "b":
+				calls.Add(1)
+				<-release
+				return s.Input, ctx.Err()
+			case nodeName == "c" && s.Input == 0:
+				<-failC
+				return 0, errors.New("leader failed")
+			}
+			return s.Input + len(s.DependNodeResult), nil
+		}
+	})
+	d.SetNodeSingleflight("b", func(s *State[int, int]) (string, error) {
+		return "lookup", nil
+	})
+	leader := make(chan *RunResult[int])
+	go func() {
+		leader <- d.RunAsyncResult(context.TODO(), 0)
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	follower := make(chan *RunResult[int])
+	go func() {
+		follower <- d.RunAsyncResult(context.TODO(), 1)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// 发起方的运行失败后共享的执行继续, 等待的运行不受影响
+	close(failC)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if res := <-leader; res.Err == nil || res.Nodes["b"].Shared {
+		t.Fatal(res.Err)
+	}
+	if res := <-follower; res.Err != nil || !res.Nodes["b"].Shared || res.Output != 3 {
+		t.Fatal(res.Err, res.Output)
+	}
+	if calls.Load() != 1 {
+		t.Fatal(calls.Load())
+	}
+}
+
+func TestSingleflight_RunLeaderCancelled(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	d := New[int, int]().SetGraph(newDiamondGraph())
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			if nodeName == "b" {
+				calls.Add(1)
+				<-release
+			}
+			return s.Input + len(s.DependNodeResult), ctx.Err()
+		}
+	})
+	d.SetSingleflight(func(k int) string { return "same" })
+	ctx, cancel := context.WithCancel(context.TODO())
+	leader := make(chan error)
+	go func() {
+		_, err := d.RunAsync(ctx, 1)
+		leader <- err
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	follower := make(chan int)
+	go func() {
+		v, _ := d.RunSync(context.TODO(), 1)
+		follower <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// 第一个调用方断开后立即返回, 其他调用方仍然得到结果
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	close(release)
+	if v := <-follower; v != 3 || calls.Load() != 1 {
+		t.Fatal(v, calls.Load())
+	}
+}
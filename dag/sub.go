@@ -27,6 +27,9 @@ func (r *nodeHelper) PushPending(id uint32) {
 	r.pending[id] = struct{}{}
 
 }
+func (r *nodeHelper) Remaining() int {
+	return len(r.pending) + len(r.processing)
+}
 func (r *nodeHelper) CheckPrepare() []uint32 {
 	var ret []uint32
 	for id := range r.pending {
@@ -0,0 +1,234 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+const (
+	AliasLatest = "latest"
+	AliasStable = "stable"
+	AliasCanary = "canary"
+)
+
+var (
+	ErrVersionNotFound = errors.New("dag version not found")
+	ErrNoPrevious      = errors.New("no previous version to roll back to")
+)
+
+// DagVersion 某个名字下的一个已发布版本
+type DagVersion[K, V any] struct {
+	Name        string
+	Version     int
+	Fingerprint string
+	Created     time.Time
+	Dag         *Dag[K, V]
+}
+
+type versionEntry[K, V any] struct {
+	versions []*DagVersion[K, V]
+	aliases  map[string]int
+	// history 记录别名此前指向的版本, 用于回滚
+	history map[string][]int
+}
+
+// VersionRegistry 按名字保存 dag 的多个版本, 通过别名(stable/canary 等)选择运行哪一个.
+// 所有修改在同一把锁下完成, 对运行方来说别名的切换是原子的
+type VersionRegistry[K, V any] struct {
+	protect sync.RWMutex
+	entries map[string]*versionEntry[K, V]
+}
+
+func NewVersionRegistry[K, V any]() *VersionRegistry[K, V] {
+	return &VersionRegistry[K, V]{entries: make(map[string]*versionEntry[K, V])}
+}
+
+func (r *Dag[K, V]) Fingerprint() string {
+	return graph.Fingerprint(r.graph.Load())
+}
+
+// Publish 以 d.Name() 发布一个新版本并指向 latest. 同一个 Dag 在图没有变化时重复发布返回已有版本并重新指向 latest,
+// 不同的 Dag 即使图相同(处理函数可能不同)也产生新版本
+func (r *VersionRegistry[K, V]) Publish(d *Dag[K, V]) (*DagVersion[K, V], error) {
+	if d == nil || d.graph.Load() == nil {
+		return nil, ErrDagNotFound
+	}
+	fp := d.Fingerprint()
+	r.protect.Lock()
+	defer r.protect.Unlock()
+	e := r.entries[d.Name()]
+	if e == nil {
+		e = &versionEntry[K, V]{aliases: make(map[string]int), history: make(map[string][]int)}
+		r.entries[d.Name()] = e
+	}
+	for _, v := range e.versions {
+		if v.Dag == d && v.Fingerprint == fp {
+			e.setAlias(AliasLatest, v.Version)
+			return v, nil
+		}
+	}
+	v := &DagVersion[K, V]{
+		Name:        d.Name(),
+		Version:     len(e.versions) + 1,
+		Fingerprint: fp,
+		Created:     time.Now(),
+		Dag:         d,
+	}
+	e.versions = append(e.versions, v)
+	e.setAlias(AliasLatest, v.Version)
+	return v, nil
+}
+
+func (e *versionEntry[K, V]) setAlias(alias string, version int) {
+	if old, ok := e.aliases[alias]; ok {
+		if old == version {
+			return
+		}
+		e.history[alias] = append(e.history[alias], old)
+	}
+	e.aliases[alias] = version
+}
+
+func (e *versionEntry[K, V]) find(ref string) (*DagVersion[K, V], bool) {
+	if v, ok := e.aliases[ref]; ok {
+		return e.versions[v-1], true
+	}
+	// 不存在的版本号继续按指纹前缀查找, 指纹前缀可能全是数字
+	if n, err := strconv.Atoi(strings.TrimPrefix(ref, "v")); err == nil && n >= 1 && n <= len(e.versions) {
+		return e.versions[n-1], true
+	}
+	// 指纹前缀, 需唯一. 多个版本的图相同时取最新的版本
+	var found *DagVersion[K, V]
+	for _, v := range e.versions {
+		if len(ref) >= 6 && strings.HasPrefix(v.Fingerprint, ref) {
+			if found != nil && found.Fingerprint != v.Fingerprint {
+				return nil, false
+			}
+			found = v
+		}
+	}
+	return found, found != nil
+}
+
+func (r *VersionRegistry[K, V]) entry(name string) (*versionEntry[K, V], error) {
+	e := r.entries[name]
+	if e == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDagNotFound, name)
+	}
+	return e, nil
+}
+
+// SetAlias 让别名指向 ref 对应的版本, ref 可以是别名, 版本号(3 或 v3)或指纹前缀
+func (r *VersionRegistry[K, V]) SetAlias(name, alias, ref string) error {
+	r.protect.Lock()
+	defer r.protect.Unlock()
+	e, err := r.entry(name)
+	if err != nil {
+		return err
+	}
+	v, ok := e.find(ref)
+	if !ok {
+		return fmt.Errorf("%w: %s@%s", ErrVersionNotFound, name, ref)
+	}
+	e.setAlias(alias, v.Version)
+	return nil
+}
+
+// Promote 让 to 指向 from 当前的版本, 例如 Promote(name, "canary", "stable")
+func (r *VersionRegistry[K, V]) Promote(name, from, to string) error {
+	return r.SetAlias(name, to, from)
+}
+
+// Rollback 让别名回到上一次指向的版本
+func (r *VersionRegistry[K, V]) Rollback(name, alias string) (*DagVersion[K, V], error) {
+	r.protect.Lock()
+	defer r.protect.Unlock()
+	e, err := r.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	h := e.history[alias]
+	if len(h) == 0 {
+		return nil, fmt.Errorf("%w: %s@%s", ErrNoPrevious, name, alias)
+	}
+	prev := h[len(h)-1]
+	e.history[alias] = h[:len(h)-1]
+	e.aliases[alias] = prev
+	return e.versions[prev-1], nil
+}
+
+// Resolve 查找 name 下 ref 对应的版本, ref 为空时依次尝试 stable 和 latest
+func (r *VersionRegistry[K, V]) Resolve(name, ref string) (*DagVersion[K, V], error) {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	e, err := r.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		ref = AliasLatest
+		if _, ok := e.aliases[AliasStable]; ok {
+			ref = AliasStable
+		}
+	}
+	v, ok := e.find(ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@%s", ErrVersionNotFound, name, ref)
+	}
+	return v, nil
+}
+
+func (r *VersionRegistry[K, V]) Versions(name string) []*DagVersion[K, V] {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	e := r.entries[name]
+	if e == nil {
+		return nil
+	}
+	return append([]*DagVersion[K, V](nil), e.versions...)
+}
+
+// Aliases 返回别名到版本号的映射
+func (r *VersionRegistry[K, V]) Aliases(name string) map[string]int {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	ret := make(map[string]int)
+	if e := r.entries[name]; e != nil {
+		for k, v := range e.aliases {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func (r *VersionRegistry[K, V]) Names() []string {
+	r.protect.RLock()
+	defer r.protect.RUnlock()
+	ret := make([]string, 0, len(r.entries))
+	for k := range r.entries {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Run 解析 ref 后运行对应版本, 结果中记录实际执行的版本
+func (r *VersionRegistry[K, V]) Run(ctx context.Context, name, ref string, k K) *RunResult[V] {
+	v, err := r.Resolve(name, ref)
+	if err != nil {
+		return &RunResult[V]{Err: err, Nodes: map[string]*NodeReport{}}
+	}
+	res := v.Dag.RunAsyncResult(ctx, k)
+	res.Version = v.Version
+	// 热加载后实际执行的图可能与发布时不同
+	res.Fingerprint = graph.Fingerprint(res.graph)
+	return res
+}
@@ -0,0 +1,121 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func newConstDag(output int, extra ...*graph.Node) *Dag[int, int] {
+	g := newDiamondGraph()
+	for _, n := range extra {
+		g.AddNode(n)
+	}
+	d := New[int, int]().SetGraph(g).SetName("flow")
+	d.RegisterFunc(func(nodeName string, id uint32) HandlerFunc[int, int] {
+		return func(ctx context.Context, s *State[int, int]) (int, error) {
+			return output, nil
+		}
+	})
+	return d
+}
+
+func TestVersionRegistry(t *testing.T) {
+	reg := NewVersionRegistry[int, int]()
+	v1, err := reg.Publish(newConstDag(1))
+	if err != nil || v1.Version != 1 {
+		t.Fatal(v1, err)
+	}
+	// 同一个 Dag 重复发布不产生新版本
+	if again, _ := reg.Publish(v1.Dag); again != v1 {
+		t.Fatal("same dag should reuse version")
+	}
+	v2, _ := reg.Publish(newConstDag(2, &graph.Node{Name: "e"}))
+	if v2.Version != 2 || v2.Fingerprint == v1.Fingerprint {
+		t.Fatal(v2)
+	}
+	if err := reg.SetAlias("flow", AliasStable, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.SetAlias("flow", AliasCanary, AliasLatest); err != nil {
+		t.Fatal(err)
+	}
+	run := func(ref string) *RunResult[int] {
+		return reg.Run(context.TODO(), "flow", ref, 0)
+	}
+	if res := run(""); res.Err != nil || res.Output != 1 || res.Version != 1 {
+		t.Fatal(res.Output, res.Version, res.Err)
+	}
+	if res := run(AliasCanary); res.Output != 2 || res.Version != 2 || res.Fingerprint != v2.Fingerprint {
+		t.Fatal(res.Output, res.Version)
+	}
+	if res := run(v1.Fingerprint[:8]); res.Version != 1 {
+		t.Fatal(res.Version)
+	}
+	if err := reg.Promote("flow", AliasCanary, AliasStable); err != nil {
+		t.Fatal(err)
+	}
+	if res := run(AliasStable); res.Version != 2 {
+		t.Fatal(res.Version)
+	}
+	if v, err := reg.Rollback("flow", AliasStable); err != nil || v.Version != 1 {
+		t.Fatal(v, err)
+	}
+	if _, err := reg.Rollback("flow", AliasStable); !errors.Is(err, ErrNoPrevious) {
+		t.Fatal(err)
+	}
+	if res := run("v9"); !errors.Is(res.Err, ErrVersionNotFound) {
+		t.Fatal(res.Err)
+	}
+	if res := reg.Run(context.TODO(), "missing", "", 0); !errors.Is(res.Err, ErrDagNotFound) {
+		t.Fatal(res.Err)
+	}
+
+	t.Run("same graph new handlers", func(t *testing.T) {
+		reg := NewVersionRegistry[int, int]()
+		v1, _ := reg.Publish(newConstDag(1))
+		v2, _ := reg.Publish(newConstDag(2))
+		if v2 == v1 || v2.Version != 2 || v2.Fingerprint != v1.Fingerprint {
+			t.Fatal(v2)
+		}
+		if res := reg.Run(context.TODO(), "flow", AliasLatest, 0); res.Output != 2 || res.Version != 2 {
+			t.Fatal(res.Output, res.Version)
+		}
+		// 重新发布旧的 Dag 时 latest 指回该版本
+		if again, _ := reg.Publish(v1.Dag); again != v1 {
+			t.Fatal(again)
+		}
+		if res := reg.Run(context.TODO(), "flow", AliasLatest, 0); res.Output != 1 {
+			t.Fatal(res.Output)
+		}
+	})
+	t.Run("numeric fingerprint prefix", func(t *testing.T) {
+		e := &versionEntry[int, int]{aliases: map[string]int{}}
+		e.versions = []*DagVersion[int, int]{{Version: 1, Fingerprint: "123456abcdef"}, {Version: 2, Fingerprint: "abcdef123456"}}
+		for ref, want := range map[string]int{"123456": 1, "2": 2, "v1": 1, "abcdef": 2} {
+			if v, ok := e.find(ref); !ok || v.Version != want {
+				t.Fatal(ref, v)
+			}
+		}
+		if _, ok := e.find("v9"); ok {
+			t.Fatal("v9")
+		}
+	})
+	t.Run("fingerprint after reload", func(t *testing.T) {
+		reg := NewVersionRegistry[int, int]()
+		d := newConstDag(1)
+		v, _ := reg.Publish(d)
+		g := newDiamondGraph()
+		g.AddNode(&graph.Node{Name: "e"})
+		if err := d.Reload(g); err != nil {
+			t.Fatal(err)
+		}
+		// 记录实际执行的图而不是发布时的图
+		res := reg.Run(context.TODO(), "flow", "", 0)
+		if res.Err != nil || res.Fingerprint == v.Fingerprint || res.Fingerprint != d.Fingerprint() {
+			t.Fatal(res.Err, res.Fingerprint)
+		}
+	})
+}
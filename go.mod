@@ -5,4 +5,7 @@ go 1.23.2
 require (
 	go.uber.org/goleak v1.3.0
 	golang.org/x/sync v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/kr/text v0.2.0 // indirect
@@ -0,0 +1,236 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Edge 以节点名表示的边
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (e Edge) String() string {
+	return e.From + " -> " + e.To
+}
+
+// FieldChange 节点上一个字段的变化, Field 为 handler, params 或 attr.<key>
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type NodeChange struct {
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffReport 两个版本的图按节点名比较的结果
+type DiffReport struct {
+	AddedNodes   []string     `json:"addedNodes"`
+	RemovedNodes []string     `json:"removedNodes"`
+	ChangedNodes []NodeChange `json:"changedNodes"`
+	AddedEdges   []Edge       `json:"addedEdges"`
+	RemovedEdges []Edge       `json:"removedEdges"`
+	// Impacted 新版本中位于变化下游的节点, 不含直接变化的节点
+	Impacted []string `json:"impacted"`
+}
+
+// Diff 比较 a(旧) 和 b(新). 节点按名字匹配, 因为解码时 id 可能是自动分配的
+func Diff(a, b *Graph) *DiffReport {
+	d := &DiffReport{
+		AddedNodes:   []string{},
+		RemovedNodes: []string{},
+		ChangedNodes: []NodeChange{},
+		AddedEdges:   []Edge{},
+		RemovedEdges: []Edge{},
+		Impacted:     []string{},
+	}
+	na, nb := nodesByName(a), nodesByName(b)
+	seeds := make(map[string]struct{})
+	for _, name := range sortedKeys(nb) {
+		old, ok := na[name]
+		if !ok {
+			d.AddedNodes = append(d.AddedNodes, name)
+			seeds[name] = struct{}{}
+			continue
+		}
+		if changes := diffNode(old, nb[name]); len(changes) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, NodeChange{Name: name, Changes: changes})
+			seeds[name] = struct{}{}
+		}
+	}
+	for _, name := range sortedKeys(na) {
+		if _, ok := nb[name]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, name)
+		}
+	}
+	ea, eb := edgeSet(a), edgeSet(b)
+	for _, e := range sortedEdgeKeys(eb) {
+		if _, ok := ea[e]; !ok {
+			d.AddedEdges = append(d.AddedEdges, e)
+			seeds[e.To] = struct{}{}
+		}
+	}
+	for _, e := range sortedEdgeKeys(ea) {
+		if _, ok := eb[e]; !ok {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+			if _, ok := nb[e.To]; ok {
+				seeds[e.To] = struct{}{}
+			}
+		}
+	}
+	// 从变化的节点出发沿新版本的边找出所有下游节点
+	impacted := make(map[string]struct{})
+	var queue []*Node
+	for name := range seeds {
+		queue = append(queue, nb[name])
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for next := range b.Next[n.Id] {
+			nn := b.FindNode(next)
+			if _, ok := impacted[nn.Name]; ok {
+				continue
+			}
+			impacted[nn.Name] = struct{}{}
+			queue = append(queue, nn)
+		}
+	}
+	for _, name := range sortedKeys(impacted) {
+		if _, ok := seeds[name]; !ok {
+			d.Impacted = append(d.Impacted, name)
+		}
+	}
+	return d
+}
+
+func (d *DiffReport) Empty() bool {
+	return len(d.AddedNodes)+len(d.RemovedNodes)+len(d.ChangedNodes)+len(d.AddedEdges)+len(d.RemovedEdges) == 0
+}
+
+// Text 输出类似 diff 的文本报告
+func (d *DiffReport) Text() string {
+	var buf strings.Builder
+	for _, v := range d.AddedNodes {
+		buf.WriteString("+ node " + v + "\n")
+	}
+	for _, v := range d.RemovedNodes {
+		buf.WriteString("- node " + v + "\n")
+	}
+	for _, v := range d.ChangedNodes {
+		var parts []string
+		for _, c := range v.Changes {
+			parts = append(parts, fmt.Sprintf("%s %q -> %q", c.Field, c.Old, c.New))
+		}
+		buf.WriteString("~ node " + v.Name + ": " + strings.Join(parts, "; ") + "\n")
+	}
+	for _, v := range d.AddedEdges {
+		buf.WriteString("+ edge " + v.String() + "\n")
+	}
+	for _, v := range d.RemovedEdges {
+		buf.WriteString("- edge " + v.String() + "\n")
+	}
+	if len(d.Impacted) > 0 {
+		buf.WriteString("impacted: " + strings.Join(d.Impacted, ", ") + "\n")
+	}
+	return buf.String()
+}
+
+func (d *DiffReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func diffNode(a, b *Node) []FieldChange {
+	var ret []FieldChange
+	if a.Handler != b.Handler {
+		ret = append(ret, FieldChange{Field: "handler", Old: a.Handler, New: b.Handler})
+	}
+	if !reflect.DeepEqual(normalizeParams(a.Params), normalizeParams(b.Params)) {
+		ret = append(ret, FieldChange{Field: "params", Old: canonicalJson(a.Params), New: canonicalJson(b.Params)})
+	}
+	keys := make(map[string]struct{})
+	for k := range a.Attrs {
+		keys[k] = struct{}{}
+	}
+	for k := range b.Attrs {
+		keys[k] = struct{}{}
+	}
+	for _, k := range sortedKeys(keys) {
+		if a.Attr(k) != b.Attr(k) {
+			ret = append(ret, FieldChange{Field: "attr." + k, Old: a.Attr(k), New: b.Attr(k)})
+		}
+	}
+	return ret
+}
+
+// normalizeParams 经 json 往返消除 int/float64 等类型差异
+func normalizeParams(params map[string]any) any {
+	if len(params) == 0 {
+		return nil
+	}
+	var ret any
+	b, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	_ = json.Unmarshal(b, &ret)
+	return ret
+}
+
+// canonicalJson map 的键按顺序输出, 结果稳定
+func canonicalJson(v any) string {
+	if m, ok := v.(map[string]any); ok && len(m) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func nodesByName(g *Graph) map[string]*Node {
+	ret := make(map[string]*Node, len(g.NodeIdMapping))
+	for _, n := range g.NodeIdMapping {
+		ret[n.Name] = n
+	}
+	return ret
+}
+
+func edgeSet(g *Graph) map[Edge]struct{} {
+	ret := make(map[Edge]struct{})
+	for _, e := range g.GetEdgeList() {
+		ret[Edge{From: g.FindNode(e[0]).Name, To: g.FindNode(e[1]).Name}] = struct{}{}
+	}
+	return ret
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func sortedEdgeKeys(m map[Edge]struct{}) []Edge {
+	ret := make([]Edge, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].From != ret[j].From {
+			return ret[i].From < ret[j].From
+		}
+		return ret[i].To < ret[j].To
+	})
+	return ret
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := NewGraph(
+		WithNodes(
+			&Node{Name: "start"},
+			&Node{Name: "users", Handler: "sql", Params: map[string]any{"limit": 10}},
+			(&Node{Name: "orders"}).SetAttr("owner", "ops"),
+			&Node{Name: "join"},
+			&Node{Name: "report"},
+		),
+		WithDependOn("users", "start"),
+		WithDependOn("orders", "start"),
+		WithDependOn("join", "users", "orders"),
+		WithDependOn("report", "join"),
+	)
+	// 新版本使用不同的 id 分配顺序
+	b := NewGraph(
+		WithNodes(
+			&Node{Name: "audit"},
+			&Node{Name: "report"},
+			&Node{Name: "join"},
+			(&Node{Name: "orders"}).SetAttr("owner", "dev"),
+			&Node{Name: "users", Handler: "sql", Params: map[string]any{"limit": float64(10)}},
+			&Node{Name: "start"},
+		),
+		WithDependOn("users", "start"),
+		WithDependOn("orders", "start"),
+		WithDependOn("join", "users", "orders"),
+		WithDependOn("report", "join"),
+		WithDependOn("audit", "start"),
+	)
+	d := Diff(a, b)
+	expect := `+ node audit
+~ node orders: attr.owner "ops" -> "dev"
++ edge start -> audit
+impacted: join, report
+`
+	if d.Text() != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", d.Text(), expect)
+	}
+	if Diff(a, a).Empty() != true || d.Empty() {
+		t.Error("empty check")
+	}
+
+	back := Diff(b, a)
+	if len(back.RemovedNodes) != 1 || len(back.RemovedEdges) != 1 || len(back.Impacted) != 2 {
+		t.Fatal(back.Text())
+	}
+	js, err := d.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DiffReport
+	if err := json.Unmarshal(js, &decoded); err != nil || decoded.ChangedNodes[0].Changes[0].New != "dev" {
+		t.Fatal(err, string(js))
+	}
+}
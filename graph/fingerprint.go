@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+type fingerprintNode struct {
+	Name    string            `json:"name"`
+	Handler string            `json:"handler,omitempty"`
+	Params  any               `json:"params,omitempty"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Fingerprint 返回图内容的 sha256. 只与节点名, handler, params, 属性和边有关,
+// 与节点 id, 声明顺序和图名无关
+func Fingerprint(g *Graph) string {
+	var content struct {
+		Nodes []fingerprintNode `json:"nodes"`
+		Edges []Edge            `json:"edges"`
+	}
+	for _, n := range g.NodeIdMapping {
+		var attrs map[string]string
+		if len(n.Attrs) > 0 {
+			attrs = n.Attrs
+		}
+		content.Nodes = append(content.Nodes, fingerprintNode{
+			Name:    n.Name,
+			Handler: n.Handler,
+			Params:  normalizeParams(n.Params),
+			Attrs:   attrs,
+		})
+	}
+	sort.Slice(content.Nodes, func(i, j int) bool {
+		return content.Nodes[i].Name < content.Nodes[j].Name
+	})
+	content.Edges = sortedEdgeKeys(edgeSet(g))
+	// encoding/json 按键排序输出 map, 结果是规范的
+	b, _ := json.Marshal(content)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,32 @@
+package graph
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	a := NewGraph(
+		WithNodes(
+			&Node{Name: "a", Params: map[string]any{"n": 1}},
+			(&Node{Name: "b"}).SetAttr("k", "v"),
+			&Node{Name: "c"},
+		),
+		WithDependOn("b", "a"),
+		WithDependOn("c", "a", "b"),
+	)
+	b := NewGraph(
+		WithNodes(
+			&Node{Name: "c"},
+			(&Node{Name: "b"}).SetAttr("k", "v"),
+			&Node{Name: "a", Params: map[string]any{"n": float64(1)}},
+		),
+		WithDependOn("c", "b", "a"),
+		WithDependOn("b", "a"),
+	)
+	b.GraphName = "renamed"
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Fatal("fingerprint depends on order")
+	}
+	b.FindNodeByName("b").SetAttr("k", "w")
+	if Fingerprint(a) == Fingerprint(b) {
+		t.Fatal("attr change not detected")
+	}
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -24,6 +25,40 @@ func (r *Graph) GetEdgeList() [][]uint32{
 	return ret
 }
 
+// Nodes 按 Id 升序返回全部节点
+func (r *Graph) Nodes() []*Node {
+	ret := make([]*Node, 0, len(r.NodeIdMapping))
+	for _, v := range r.NodeIdMapping {
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Id < ret[j].Id
+	})
+	return ret
+}
+
+// Levels 按拓扑层级返回节点, 同一层级的节点互不依赖, 层内按 Id 排序
+func (r *Graph) Levels() ([][]*Node, error) {
+	it := r.TopoIterator()
+	defer func() {
+		it.Reset()
+		GraphStatePool.Put(it)
+	}()
+	var ret [][]*Node
+	for it.HasNext() {
+		nodes, err := it.Next()
+		if err != nil {
+			return ret, err
+		}
+		level := append([]*Node(nil), nodes...)
+		sort.Slice(level, func(i, j int) bool {
+			return level[i].Id < level[j].Id
+		})
+		ret = append(ret, level)
+	}
+	return ret, nil
+}
+
 func (r *Graph) FindNode(id uint32) *Node {
 	return r.NodeIdMapping[id]
 }
@@ -36,8 +71,26 @@ func (r *Graph) FindNodeByName(s string) *Node {
 }
 
 type Node struct {
-	Id   uint32
-	Name string
+	Id    uint32
+	Name  string
+	Attrs map[string]string
+	// Handler 为处理函数类型名, Params 为其配置参数, 由 dag.Registry 绑定
+	Handler string
+	Params  map[string]any
+}
+
+func (n *Node) Attr(key string) string {
+	if n.Attrs == nil {
+		return ""
+	}
+	return n.Attrs[key]
+}
+func (n *Node) SetAttr(key, val string) *Node {
+	if n.Attrs == nil {
+		n.Attrs = make(map[string]string)
+	}
+	n.Attrs[key] = val
+	return n
 }
 
 type Option func(c *Graph)
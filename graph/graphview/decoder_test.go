@@ -2,9 +2,14 @@ package graphview
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/opengeektech/go-dag/graph"
 	"github.com/opengeektech/go-dag/utils"
 )
 
@@ -37,3 +42,101 @@ func TestJsonDecoder_Decode(t *testing.T) {
 	})
 
 }
+
+func TestJsonDecoder_Validate(t *testing.T) {
+	t.Run("legacy_single_graph", func(t *testing.T) {
+		content := `{
+			"graphName": "graph",
+			"graphType": "dag",
+			"nodes": [
+				{"id": 1, "name": "node1", "dependOn": [2, 3]},
+				{"id": 2, "name": "node2", "dependOn": [3]},
+				{"id": 3, "name": "node3", "dependOn": []}
+			]
+		}`
+		h := JsonDecoder{Strict: true}
+		g, err := h.Decode(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(g) != 1 || g[0].GraphName != "graph" || len(g[0].NodeIdMapping) != 3 {
+			t.Fatal("migrate failed ", utils.JsonString(g))
+		}
+	})
+	t.Run("unsupported_version", func(t *testing.T) {
+		var h JsonDecoder
+		_, err := h.Decode(strings.NewReader(`{"version": 99, "graphList": []}`))
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatal("expect version error ", err)
+		}
+	})
+	t.Run("strict_unknown_fields", func(t *testing.T) {
+		content := `{"graphList": [{"graphName": "g", "color": "red", "nodes": [{"name": "a", "dependsOn": ["b"]}]}]}`
+		var lenient JsonDecoder
+		if _, err := lenient.Decode(strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+		strict := JsonDecoder{Strict: true}
+		_, err := strict.Decode(strings.NewReader(content))
+		var errs ContentErrors
+		if !errors.As(err, &errs) || len(errs) != 2 {
+			t.Fatal("expect 2 unknown fields ", err)
+		}
+		t.Log(err)
+	})
+	t.Run("multi_error", func(t *testing.T) {
+		content := `{"graphList": [
+			{"graphName": "g1", "nodes": [
+				{"id": 1, "name": "a"},
+				{"id": 1, "name": "b"},
+				{"id": 2, "name": "a"},
+				{"id": 3, "name": "c", "dependOnName": ["missing"]},
+				{"id": 4, "name": "d", "dependOn": [9]}
+			]},
+			{"graphName": "g2", "nodes": [
+				{"name": "x", "dependOnName": ["y"]},
+				{"name": "y", "dependOnName": ["x"]}
+			]}
+		]}`
+		var h JsonDecoder
+		_, err := h.Decode(strings.NewReader(content))
+		if !errors.Is(err, ErrIllegalContent) {
+			t.Fatal("expect illegal content ", err)
+		}
+		var errs ContentErrors
+		if !errors.As(err, &errs) || len(errs) != 5 {
+			t.Fatal("expect 5 problems ", err)
+		}
+		if !errors.Is(err, graph.ErrCycleDetected) {
+			t.Error("cycle not reported")
+		}
+		t.Log(err)
+	})
+}
+
+func TestJsonSchema_InSync(t *testing.T) {
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JsonSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, props map[string]any, v any) {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			key, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if _, ok := props[key]; !ok {
+				t.Errorf("schema %s missing field %s", name, key)
+			}
+		}
+		if len(props) != typ.NumField() {
+			t.Errorf("schema %s has %d fields, struct has %d", name, len(props), typ.NumField())
+		}
+	}
+	check("root", schema.Properties, graphList{})
+	check("graph", schema.Defs["graph"].Properties, graphJsonContent{})
+	check("node", schema.Defs["node"].Properties, nodeJsonContent{})
+}
@@ -0,0 +1,511 @@
+package graphview
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// DotDecoder 解析常用的 DOT 子集: digraph, 节点语句, a -> b -> c 链式边, 属性列表,
+// node/edge/graph 默认属性以及 subgraph. 节点属性以 DotAttrPrefix 为前缀写入节点元数据,
+// cluster 子图的 label 写入 GroupAttr, 与 GetDotGraphDesc 的输出可以互相转换.
+// 边属性没有对应的存储位置, 解析后丢弃.
+type DotDecoder struct {
+}
+
+func (d *DotDecoder) Decode(r io.Reader) ([]*Graph, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{lex: &dotLexer{src: []rune(string(content)), line: 1}}
+	p.next()
+	var ret []*Graph
+	for p.tok.kind != dotTokEOF {
+		g, err := p.parseGraph()
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, g)
+	}
+	return ret, nil
+}
+
+type dotTokenKind int
+
+const (
+	dotTokEOF dotTokenKind = iota
+	dotTokID
+	dotTokPunct
+	dotTokArrow
+)
+
+type dotToken struct {
+	kind   dotTokenKind
+	text   string
+	quoted bool
+	line   int
+}
+
+type dotLexer struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (l *dotLexer) peek(offset int) rune {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *dotLexer) skip() {
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '\n':
+			l.line++
+			l.pos++
+		case unicode.IsSpace(ch):
+			l.pos++
+		case ch == '/' && l.peek(1) == '/', ch == '#' && (l.pos == 0 || l.src[l.pos-1] == '\n'):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case ch == '/' && l.peek(1) == '*':
+			l.pos += 2
+			for l.pos < len(l.src) && !(l.src[l.pos] == '*' && l.peek(1) == '/') {
+				if l.src[l.pos] == '\n' {
+					l.line++
+				}
+				l.pos++
+			}
+			l.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func (l *dotLexer) next() (dotToken, error) {
+	l.skip()
+	if l.pos >= len(l.src) {
+		return dotToken{kind: dotTokEOF, line: l.line}, nil
+	}
+	ch := l.src[l.pos]
+	tok := dotToken{line: l.line}
+	switch {
+	case ch == '-' && (l.peek(1) == '>' || l.peek(1) == '-'):
+		tok.kind, tok.text = dotTokArrow, string(l.src[l.pos:l.pos+2])
+		l.pos += 2
+	case strings.ContainsRune("{}[]=;,:", ch):
+		tok.kind, tok.text = dotTokPunct, string(ch)
+		l.pos++
+	case ch == '"':
+		s, err := l.quoted()
+		if err != nil {
+			return tok, err
+		}
+		// "a" + "b" 拼接
+		for {
+			save, line := l.pos, l.line
+			l.skip()
+			if l.peek(0) != '+' {
+				l.pos, l.line = save, line
+				break
+			}
+			l.pos++
+			l.skip()
+			if l.peek(0) != '"' {
+				return tok, fmt.Errorf("%w,line %d: expect string after '+'", ErrIllegalContent, l.line)
+			}
+			more, err := l.quoted()
+			if err != nil {
+				return tok, err
+			}
+			s += more
+		}
+		tok.kind, tok.text, tok.quoted = dotTokID, s, true
+	case ch == '<':
+		depth := 0
+		start := l.pos
+		for ; l.pos < len(l.src); l.pos++ {
+			switch l.src[l.pos] {
+			case '<':
+				depth++
+			case '>':
+				depth--
+			case '\n':
+				l.line++
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if depth != 0 {
+			return tok, fmt.Errorf("%w,line %d: unterminated html string", ErrIllegalContent, tok.line)
+		}
+		l.pos++
+		tok.kind, tok.text, tok.quoted = dotTokID, string(l.src[start+1:l.pos-1]), true
+	case ch == '_' || ch == '.' || ch == '-' || unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch >= 0x80:
+		start := l.pos
+		for l.pos < len(l.src) {
+			c := l.src[l.pos]
+			if c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c) || c >= 0x80 ||
+				(c == '-' && l.pos == start) {
+				l.pos++
+				continue
+			}
+			break
+		}
+		tok.kind, tok.text = dotTokID, string(l.src[start:l.pos])
+	default:
+		return tok, fmt.Errorf("%w,line %d: unexpected character %q", ErrIllegalContent, l.line, ch)
+	}
+	return tok, nil
+}
+
+func (l *dotLexer) quoted() (string, error) {
+	line := l.line
+	l.pos++
+	var buf strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '"':
+			l.pos++
+			return buf.String(), nil
+		case ch == '\\' && l.pos+1 < len(l.src):
+			next := l.src[l.pos+1]
+			switch next {
+			case '"', '\\':
+				buf.WriteRune(next)
+			case 'n':
+				buf.WriteRune('\n')
+			case '\n':
+				// 行尾续行
+				l.line++
+			default:
+				buf.WriteRune(ch)
+				buf.WriteRune(next)
+			}
+			l.pos += 2
+		default:
+			if ch == '\n' {
+				l.line++
+			}
+			buf.WriteRune(ch)
+			l.pos++
+		}
+	}
+	return "", fmt.Errorf("%w,line %d: unterminated string", ErrIllegalContent, line)
+}
+
+type dotNode struct {
+	node  *Node
+	group bool
+}
+
+type dotScope struct {
+	nodeDefaults map[string]string
+	cluster      bool
+	name         string
+	label        string
+	members      []*dotNode
+}
+
+type dotParser struct {
+	lex   *dotLexer
+	tok   dotToken
+	err   error
+	nodes map[string]*dotNode
+	order []*dotNode
+	edges [][2]*dotNode
+}
+
+func (p *dotParser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = dotToken{kind: dotTokEOF, line: p.lex.line}
+	}
+}
+
+func (p *dotParser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("%w,line %d: %s", ErrIllegalContent, p.tok.line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotParser) isPunct(s string) bool {
+	return p.tok.kind == dotTokPunct && p.tok.text == s
+}
+
+func (p *dotParser) isKeyword(s string) bool {
+	return p.tok.kind == dotTokID && !p.tok.quoted && strings.EqualFold(p.tok.text, s)
+}
+
+func (p *dotParser) expect(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf("expect %q, got %q", s, p.tok.text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *dotParser) parseGraph() (*Graph, error) {
+	p.nodes = make(map[string]*dotNode)
+	p.order = nil
+	p.edges = nil
+	if p.isKeyword("strict") {
+		p.next()
+	}
+	if p.isKeyword("graph") {
+		return nil, p.errorf("undirected graph is not supported")
+	}
+	if !p.isKeyword("digraph") {
+		return nil, p.errorf("expect digraph, got %q", p.tok.text)
+	}
+	p.next()
+	name := ""
+	if p.tok.kind == dotTokID {
+		name = p.tok.text
+		p.next()
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	scope := &dotScope{nodeDefaults: map[string]string{}}
+	if err := p.parseStmtList(scope); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	g := graph.NewGraph()
+	g.GraphName = name
+	for _, n := range p.order {
+		g.AddNode(n.node)
+	}
+	for _, e := range p.edges {
+		if e[0] == e[1] {
+			return nil, fmt.Errorf("%w,%s %w: %s -> %s", ErrIllegalContent, name, graph.ErrCycleDetected, e[0].node.Name, e[1].node.Name)
+		}
+		g.DependOnNode(e[1].node, e[0].node)
+	}
+	if cycle := findCycle(g); len(cycle) > 0 {
+		return nil, fmt.Errorf("%w,%s %w: %s", ErrIllegalContent, name, graph.ErrCycleDetected, strings.Join(cycle, ","))
+	}
+	return g, nil
+}
+
+func (p *dotParser) parseStmtList(scope *dotScope) error {
+	for !p.isPunct("}") {
+		if p.tok.kind == dotTokEOF {
+			return p.errorf("unexpected end of content")
+		}
+		if err := p.parseStmt(scope); err != nil {
+			return err
+		}
+		if p.isPunct(";") {
+			p.next()
+		}
+	}
+	return p.err
+}
+
+func (p *dotParser) parseStmt(scope *dotScope) error {
+	switch {
+	case p.isKeyword("node"), p.isKeyword("edge"), p.isKeyword("graph"):
+		kind := strings.ToLower(p.tok.text)
+		p.next()
+		attrs, err := p.parseAttrLists()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case "node":
+			for k, v := range attrs {
+				scope.nodeDefaults[k] = v
+			}
+		case "graph":
+			if v, ok := attrs["label"]; ok {
+				scope.label = v
+			}
+		}
+		return nil
+	case p.tok.kind == dotTokID && !p.isKeyword("subgraph"):
+		id := p.tok.text
+		p.next()
+		if p.isPunct("=") {
+			p.next()
+			if p.tok.kind != dotTokID {
+				return p.errorf("expect value of %s", id)
+			}
+			if id == "label" {
+				scope.label = p.tok.text
+			}
+			p.next()
+			return nil
+		}
+		p.skipPort()
+		if p.tok.kind == dotTokArrow {
+			return p.parseEdges([]*dotNode{p.declare(id, scope)}, scope)
+		}
+		n := p.declare(id, scope)
+		attrs, err := p.parseAttrLists()
+		if err != nil {
+			return err
+		}
+		for k, v := range attrs {
+			n.node.SetAttr(DotAttrPrefix+k, v)
+		}
+		return nil
+	case p.isKeyword("subgraph"), p.isPunct("{"):
+		members, err := p.parseSubgraph(scope)
+		if err != nil {
+			return err
+		}
+		if p.tok.kind == dotTokArrow {
+			return p.parseEdges(members, scope)
+		}
+		return nil
+	}
+	return p.errorf("unexpected %q", p.tok.text)
+}
+
+// skipPort 忽略 node:port:compass
+func (p *dotParser) skipPort() {
+	for p.isPunct(":") {
+		p.next()
+		if p.tok.kind == dotTokID {
+			p.next()
+		}
+	}
+}
+
+func (p *dotParser) parseEdges(left []*dotNode, scope *dotScope) error {
+	for p.tok.kind == dotTokArrow {
+		if p.tok.text != "->" {
+			return p.errorf("undirected edge is not supported")
+		}
+		p.next()
+		var right []*dotNode
+		switch {
+		case p.isKeyword("subgraph"), p.isPunct("{"):
+			members, err := p.parseSubgraph(scope)
+			if err != nil {
+				return err
+			}
+			right = members
+		case p.tok.kind == dotTokID:
+			id := p.tok.text
+			p.next()
+			p.skipPort()
+			right = []*dotNode{p.declare(id, scope)}
+		default:
+			return p.errorf("expect node after ->")
+		}
+		for _, from := range left {
+			for _, to := range right {
+				p.edges = append(p.edges, [2]*dotNode{from, to})
+			}
+		}
+		left = right
+	}
+	// 边属性丢弃
+	_, err := p.parseAttrLists()
+	return err
+}
+
+func (p *dotParser) parseSubgraph(parent *dotScope) ([]*dotNode, error) {
+	scope := &dotScope{nodeDefaults: make(map[string]string)}
+	for k, v := range parent.nodeDefaults {
+		scope.nodeDefaults[k] = v
+	}
+	if p.isKeyword("subgraph") {
+		p.next()
+		if p.tok.kind == dotTokID {
+			scope.name = p.tok.text
+			p.next()
+		}
+	}
+	scope.cluster = strings.HasPrefix(scope.name, "cluster")
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseStmtList(scope); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	if scope.cluster {
+		group := scope.label
+		if group == "" {
+			group = strings.TrimPrefix(strings.TrimPrefix(scope.name, "cluster"), "_")
+		}
+		for _, n := range scope.members {
+			if !n.group && group != "" {
+				n.group = true
+				n.node.SetAttr(GroupAttr, group)
+			}
+		}
+	}
+	parent.members = append(parent.members, scope.members...)
+	return scope.members, nil
+}
+
+func (p *dotParser) declare(id string, scope *dotScope) *dotNode {
+	n, ok := p.nodes[id]
+	if !ok {
+		n = &dotNode{node: &Node{Name: id}}
+		for k, v := range scope.nodeDefaults {
+			n.node.SetAttr(DotAttrPrefix+k, v)
+		}
+		p.nodes[id] = n
+		p.order = append(p.order, n)
+	}
+	scope.members = append(scope.members, n)
+	return n
+}
+
+func (p *dotParser) parseAttrLists() (map[string]string, error) {
+	attrs := make(map[string]string)
+	for p.isPunct("[") {
+		p.next()
+		for !p.isPunct("]") {
+			if p.tok.kind != dotTokID {
+				return nil, p.errorf("expect attribute name, got %q", p.tok.text)
+			}
+			key := p.tok.text
+			p.next()
+			value := "true"
+			if p.isPunct("=") {
+				p.next()
+				if p.tok.kind != dotTokID {
+					return nil, p.errorf("expect value of %s", key)
+				}
+				value = p.tok.text
+				p.next()
+			}
+			attrs[key] = value
+			if p.isPunct(",") || p.isPunct(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs, p.err
+}
+
+var (
+	_ GraphContentHelper = &DotDecoder{}
+)
@@ -0,0 +1,138 @@
+package graphview
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func edgeNames(g *graph.Graph) []string {
+	var ret []string
+	for _, e := range g.GetEdgeList() {
+		ret = append(ret, g.FindNode(e[0]).Name+"->"+g.FindNode(e[1]).Name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestDotDecoder_Decode(t *testing.T) {
+	content := `
+/* workflow sketch */
+strict digraph "etl flow" {
+	rankdir=LR;
+	node [shape=box];
+	start [color="red", label="Start here"];
+	// chains and subgraph operands
+	start -> "fetch users" -> join;
+	start -> { "fetch orders" fetch:port } -> join [style=dashed];
+	subgraph cluster_load {
+		label = "load";
+		"Node:3";
+		join -> "Node:3";
+	}
+	alone
+	"quoted \"name\"" -> alone
+}
+digraph second { a -> b }
+`
+	var h DotDecoder
+	graphs, err := h.Decode(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphs) != 2 {
+		t.Fatal("graph count ", len(graphs))
+	}
+	g := graphs[0]
+	if g.GraphName != "etl flow" || len(g.NodeIdMapping) != 8 {
+		t.Fatal(g.GraphName, len(g.NodeIdMapping))
+	}
+	edges := strings.Join(edgeNames(g), " ")
+	for _, e := range []string{
+		"start->fetch users", "fetch users->join", "start->fetch orders", "start->fetch",
+		"fetch orders->join", "fetch->join", "join->Node:3", `quoted "name"->alone`,
+	} {
+		if !strings.Contains(edges, e) {
+			t.Errorf("missing edge %s in %s", e, edges)
+		}
+	}
+	start := g.FindNodeByName("start")
+	if start.Attr("dot.shape") != "box" || start.Attr("dot.color") != "red" || start.Attr("dot.label") != "Start here" {
+		t.Error("node attrs ", start.Attrs)
+	}
+	if g.FindNodeByName("Node:3").Attr(GroupAttr) != "load" || g.FindNodeByName("join").Attr(GroupAttr) != "load" {
+		t.Error("cluster group lost")
+	}
+	if g.FindNodeByName("fetch users").Attr(GroupAttr) != "" {
+		t.Error("node outside cluster grouped")
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, bad := range []string{
+			`graph g { a -- b }`,
+			`digraph g { a -> b -> a }`,
+			`digraph g { a -> b `,
+			`digraph g { a [color=] }`,
+		} {
+			_, err := h.Decode(strings.NewReader(bad))
+			if !errors.Is(err, ErrIllegalContent) {
+				t.Errorf("%s: expect illegal content, got %v", bad, err)
+			}
+		}
+	})
+}
+
+func TestDotDecoder_RoundTrip(t *testing.T) {
+	g := graph.NewGraph(
+		graph.WithNodes(
+			(&graph.Node{Name: "Node:1"}).SetAttr(GroupAttr, "load").SetAttr("dot.shape", "box"),
+			(&graph.Node{Name: "fetch data"}).SetAttr(GroupAttr, "load"),
+			&graph.Node{Name: "a-b"},
+			&graph.Node{Name: "node"},
+			&graph.Node{Name: "alone"},
+		),
+		graph.WithDependOn("fetch data", "Node:1"),
+		graph.WithDependOn("a-b", "fetch data", "Node:1"),
+		graph.WithDependOn("node", "a-b"),
+	)
+	g.GraphName = "demo"
+	first, err := GetDotGraphDesc(g, WithGroupAttr(GroupAttr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h DotDecoder
+	decoded, err := h.Decode(strings.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := decoded[0]
+	if d.GraphName != "demo" || len(d.NodeIdMapping) != len(g.NodeIdMapping) {
+		t.Fatal(d.GraphName, len(d.NodeIdMapping))
+	}
+	if strings.Join(edgeNames(d), ",") != strings.Join(edgeNames(g), ",") {
+		t.Fatal(edgeNames(d))
+	}
+	for _, n := range g.Nodes() {
+		got := d.FindNodeByName(n.Name)
+		if got == nil || len(got.Attrs) != len(n.Attrs) {
+			t.Fatalf("node %s attrs %v != %v", n.Name, got, n.Attrs)
+		}
+		for k, v := range n.Attrs {
+			if got.Attr(k) != v {
+				t.Errorf("node %s attr %s %s != %s", n.Name, k, got.Attr(k), v)
+			}
+		}
+	}
+	second, _ := GetDotGraphDesc(d, WithGroupAttr(GroupAttr))
+	again, err := h.Decode(strings.NewReader(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, _ := GetDotGraphDesc(again[0], WithGroupAttr(GroupAttr))
+	if second != third {
+		t.Fatalf("not stable:\n%s\n%s", second, third)
+	}
+}
@@ -0,0 +1,81 @@
+package graphview
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	codeGraph := graph.NewGraph(
+		graph.WithNodes(
+			(&graph.Node{Name: "start"}).SetAttr("owner", "ops"),
+			&graph.Node{Name: "A", Handler: "http", Params: map[string]any{"retry": 2}},
+			&graph.Node{Name: "B"},
+			&graph.Node{Name: "alone"},
+		),
+		graph.WithDependOn("A", "start"),
+		graph.WithDependOn("B", "A", "start"),
+	)
+	codeGraph.GraphName = "code"
+	all, err := os.ReadFile("tests/nodes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h JsonDecoder
+	fileGraphs, err := h.Decode(bytes.NewBuffer(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphs := append(fileGraphs, codeGraph)
+
+	cases := []struct {
+		name string
+		enc  GraphContentEncoder
+		dec  GraphContentHelper
+	}{
+		{"json", &JsonEncoder{Indent: "  "}, &JsonDecoder{}},
+		{"yaml", &YamlEncoder{}, &YamlDecoder{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var first bytes.Buffer
+			if err := c.enc.Encode(&first, graphs); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := c.dec.Decode(bytes.NewReader(first.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded) != len(graphs) {
+				t.Fatalf("graph count %d != %d", len(decoded), len(graphs))
+			}
+			var second bytes.Buffer
+			if err := c.enc.Encode(&second, decoded); err != nil {
+				t.Fatal(err)
+			}
+			if first.String() != second.String() {
+				t.Fatalf("not stable:\n%s\n---\n%s", first.String(), second.String())
+			}
+			g := decoded[1]
+			if g.GraphName != "code" {
+				t.Error("graph name lost", g.GraphName)
+			}
+			if n := g.FindNodeByName("start"); n == nil || n.Attr("owner") != "ops" {
+				t.Error("attrs lost")
+			}
+			if n := g.FindNodeByName("A"); n.Handler != "http" || n.Params["retry"] != float64(2) {
+				t.Error("handler lost ", n.Handler, n.Params)
+			}
+			if g.FindNodeByName("alone") == nil {
+				t.Error("isolated node lost")
+			}
+			if len(g.GetDepend(g.FindNodeByName("B").Id)) != 2 {
+				t.Error("edges lost")
+			}
+			t.Log("\n" + first.String())
+		})
+	}
+}
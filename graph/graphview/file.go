@@ -0,0 +1,35 @@
+package graphview
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DecoderFor 按文件扩展名选择解码器: .json, .yaml/.yml, .dot/.gv
+func DecoderFor(path string, strict bool) (GraphContentHelper, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return &JsonDecoder{Strict: strict}, nil
+	case ".yaml", ".yml":
+		return &YamlDecoder{JsonDecoder: JsonDecoder{Strict: strict}}, nil
+	case ".dot", ".gv":
+		return &DotDecoder{}, nil
+	}
+	return nil, fmt.Errorf("unsupported file type %s", path)
+}
+
+// DecodeFile 读取并解码图定义文件
+func DecodeFile(path string, strict bool) ([]*Graph, error) {
+	dec, err := DecoderFor(path, strict)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return dec.Decode(f)
+}
@@ -1,10 +1,12 @@
 package graphview
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/opengeektech/go-dag/graph"
 )
@@ -14,81 +16,136 @@ type GraphContentHelper interface {
 }
 
 type JsonDecoder struct {
+	// Strict 拒绝未知字段
+	Strict bool
 }
 type graphList struct {
-	GraphName string 
-	GraphId uint32
-	GraphList []graphJsonContent `json:"graphList"`
+	Version   int                `json:"version,omitempty" yaml:"version,omitempty"`
+	GraphName string             `json:"graphName,omitempty" yaml:"graphName,omitempty"`
+	GraphId   uint32             `json:"graphId,omitempty" yaml:"graphId,omitempty"`
+	GraphList []graphJsonContent `json:"graphList" yaml:"graphList"`
 }
 type graphJsonContent struct {
-	GraphName string `json:"graphName"`
-	GraphId   uint32 `json:"graphId"`
-	Nodes     []*struct {
-		Id           uint32   `json:"id"`
-		Name         string   `json:"name"`
-		DependOn     []uint32 `json:"dependOn"`
-		DependOnName []string `json:"dependOnName"`
-	} `json:"nodes"`
+	GraphName string             `json:"graphName" yaml:"graphName"`
+	GraphId   uint32             `json:"graphId,omitempty" yaml:"graphId,omitempty"`
+	Nodes     []*nodeJsonContent `json:"nodes" yaml:"nodes"`
+}
+type nodeJsonContent struct {
+	Id           uint32            `json:"id" yaml:"id"`
+	Name         string            `json:"name" yaml:"name"`
+	DependOn     []uint32          `json:"dependOn,omitempty" yaml:"dependOn,omitempty"`
+	DependOnName []string          `json:"dependOnName" yaml:"dependOnName"`
+	Attrs        map[string]string `json:"attrs,omitempty" yaml:"attrs,omitempty"`
+	Handler      string            `json:"handler,omitempty" yaml:"handler,omitempty"`
+	Params       map[string]any    `json:"params,omitempty" yaml:"params,omitempty"`
 }
 
 /*
-{
-"graphName":"graph",
-"graphType": "dag",
-"nodes": [
-
-		[
-			{
-			"id": 1,
-			"name": "node1",
-			"dependOn": [2, 3]
-			},
-			{
-			"id": 2,
-			"name": "node2",
-			"dependOn": [3]
-			},
+	{
+		"version": 1,
+		"graphList": [
 			{
-			"id": 3,
-			"name": "node3",
-			"dependOn": []
+				"graphName": "graph",
+				"nodes": [
+					{"id": 1, "name": "node1", "dependOn": [2, 3]},
+					{"id": 2, "name": "node2", "dependOn": [3]},
+					{"id": 3, "name": "node3", "dependOn": [], "handler": "http", "params": {"url": "http://127.0.0.1"}}
+				]
+			}
 		]
 	}
 */
 var (
 	ErrIllegalContent = fmt.Errorf("Illegal Content check")
 )
+
 type Graph = graph.Graph
 type Node = graph.Node
+
 func (j *JsonDecoder) Decode(r io.Reader) ([]*Graph, error) {
-	var h graphList
-	err := json.NewDecoder(r).Decode(&h)
+	var doc map[string]any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&doc)
 	if err != nil {
 		return nil, err
 	}
-	var row []*Graph
-	for _, v := range h.GraphList {
-		g, err := j.decoderow(v)
-		g.GraphName=v.GraphName
+	return j.decodeDocument(doc)
+}
+
+// decodeDocument 先做版本迁移, 严格模式下再检查未知字段
+func (j *JsonDecoder) decodeDocument(doc map[string]any) ([]*Graph, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w,empty content", ErrIllegalContent)
+	}
+	if err := Migrate(doc); err != nil {
+		return nil, err
+	}
+	var errs ContentErrors
+	if j.Strict {
+		errs = append(errs, checkUnknownFields(doc)...)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var h graphList
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if j.Strict && len(errs) == 0 {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&h); err != nil {
+		return nil, append(errs, fmt.Errorf("%w,%v", ErrIllegalContent, err))
+	}
+	row, err := j.decodeList(h)
+	if err != nil {
+		errs = append(errs, err.(ContentErrors)...)
+	}
+	if len(errs) > 0 {
+		return row, errs
+	}
+	return row, nil
+}
+func (j *JsonDecoder) decodeList(h graphList) ([]*Graph, error) {
+	var (
+		row  []*Graph
+		errs ContentErrors
+	)
+	graphNames := make(map[string]struct{})
+	for i, v := range h.GraphList {
+		at := fmt.Sprintf("graphList[%d]", i)
+		if _, ok := graphNames[v.GraphName]; ok && v.GraphName != "" {
+			errs = append(errs, fmt.Errorf("%w,%s graphName repeated %s", ErrIllegalContent, at, v.GraphName))
+		}
+		graphNames[v.GraphName] = struct{}{}
+		g, err := j.decoderow(v, at)
 		if err != nil {
-			return row, err
+			errs = append(errs, err.(ContentErrors)...)
+			continue
 		}
+		g.GraphName = v.GraphName
 		row = append(row, g)
 
 	}
+	if len(errs) > 0 {
+		return row, errs
+	}
 	return row, nil
 }
 
 // Decode implements GraphContentHelper.
-func (j *JsonDecoder) decoderow(t graphJsonContent) (*Graph, error) {
-
-	g := graph.NewGraph()
+func (j *JsonDecoder) decoderow(t graphJsonContent, at string) (*Graph, error) {
+	var errs ContentErrors
 	k := uint32(1)
 	repeated := make(map[uint32]struct{})
 	repeatedName := make(map[string]struct{})
 	namebinding := make(map[string]*Node)
 	nodeList := make(map[uint32]*Node)
-	for _, v := range t.Nodes {
+	for i, v := range t.Nodes {
+		if v == nil {
+			errs = append(errs, fmt.Errorf("%w,%s.nodes[%d] is null", ErrIllegalContent, at, i))
+			continue
+		}
 		if v.Id <= 0 {
 			v.Id = k
 			k++
@@ -96,61 +153,104 @@ func (j *JsonDecoder) decoderow(t graphJsonContent) (*Graph, error) {
 		if v.Name == "" {
 			v.Name = "Node:" + strconv.Itoa(int(v.Id))
 		}
+		where := fmt.Sprintf("%s.nodes[%d]", at, i)
 		_, ok := repeated[v.Id]
 		if ok {
-			return nil, fmt.Errorf("%w,id config illegal %s", ErrIllegalContent, v.Name)
+			errs = append(errs, fmt.Errorf("%w,%s id config illegal %s,id %d repeated", ErrIllegalContent, where, v.Name, v.Id))
 		}
 		repeated[v.Id] = struct{}{}
 		_, ok = repeatedName[v.Name]
 		if ok {
-			return nil, fmt.Errorf("%w,id config illegal %s", ErrIllegalContent, v.Name)
+			errs = append(errs, fmt.Errorf("%w,%s name config illegal %s repeated", ErrIllegalContent, where, v.Name))
 		}
 		repeatedName[v.Name] = struct{}{}
 		if len(v.DependOn) > 0 && len(v.DependOnName) > 0 {
-			return nil, fmt.Errorf("%w dependOn and dependOnName is conflict", ErrIllegalContent)
+			errs = append(errs, fmt.Errorf("%w,%s dependOn and dependOnName is conflict", ErrIllegalContent, where))
+		}
+		if ok {
+			continue
 		}
 		t := &Node{
-			Id:   v.Id,
-			Name: v.Name,
+			Id:      v.Id,
+			Name:    v.Name,
+			Attrs:   v.Attrs,
+			Handler: v.Handler,
+			Params:  v.Params,
 		}
 		namebinding[v.Name] = t
 		nodeList[v.Id] = t
 	}
-	for _, ele := range t.Nodes {
+	for i, ele := range t.Nodes {
+		if ele == nil {
+			continue
+		}
+		where := fmt.Sprintf("%s.nodes[%d]", at, i)
 		if len(ele.DependOnName) > 0 && len(ele.DependOn) == 0 {
 			for _, v := range ele.DependOnName {
-				if n, ok := namebinding[v]; !ok {
-					return nil, fmt.Errorf("%w,dependOnName config illegal %s", ErrIllegalContent, v)
+				if v == ele.Name {
+					errs = append(errs, fmt.Errorf("%w,%s node %s depend on itself", ErrIllegalContent, where, ele.Name))
+				} else if n, ok := namebinding[v]; !ok {
+					errs = append(errs, fmt.Errorf("%w,%s dependOnName config illegal %s", ErrIllegalContent, where, v))
 				} else {
 					ele.DependOn = append(ele.DependOn, n.Id)
 				}
 			}
+		} else {
+			for _, id := range ele.DependOn {
+				if id == ele.Id {
+					errs = append(errs, fmt.Errorf("%w,%s node %s depend on itself", ErrIllegalContent, where, ele.Name))
+				} else if _, ok := nodeList[id]; !ok {
+					errs = append(errs, fmt.Errorf("%w,%s dependOn node not found %d", ErrIllegalContent, where, id))
+				}
+			}
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
+	g := graph.NewGraph()
 	for _, v := range t.Nodes {
 		curr := nodeList[v.Id]
-		if curr == nil {
-			return nil, fmt.Errorf("Id error %w", ErrIllegalContent)
-		}
-		if len(v.DependOn) == 0 {
-			g.DependOnNode(curr)
-		}
 		var pre []*Node
 		for _, id := range v.DependOn {
-			nod, ok := nodeList[id]
-			if !ok {
-				return nil, fmt.Errorf("node not found %w", ErrIllegalContent)
-			}
-			// g.dependOn(curr,nod)
-			pre = append(pre, nod)
-			g.DependOnNode(curr, pre...)
+			pre = append(pre, nodeList[id])
 		}
+		g.DependOnNode(curr, pre...)
+	}
+	if cycle := findCycle(g); len(cycle) > 0 {
+		return nil, ContentErrors{fmt.Errorf("%w,%s %w: %s", ErrIllegalContent, at, graph.ErrCycleDetected, strings.Join(cycle, ","))}
 	}
 	return g, nil
 
 }
 
+// findCycle 返回无法拓扑排序的节点名
+func findCycle(g *Graph) []string {
+	it := g.TopoIterator()
+	defer func() {
+		it.Reset()
+		graph.GraphStatePool.Put(it)
+	}()
+	visited := make(map[uint32]struct{})
+	for it.HasNext() {
+		nodes, err := it.Next()
+		if err != nil {
+			break
+		}
+		for _, n := range nodes {
+			visited[n.Id] = struct{}{}
+		}
+	}
+	var ret []string
+	for _, n := range g.Nodes() {
+		if _, ok := visited[n.Id]; !ok {
+			ret = append(ret, n.Name)
+		}
+	}
+	return ret
+}
+
 var (
 	_ GraphContentHelper = &JsonDecoder{}
 )
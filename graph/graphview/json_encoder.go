@@ -0,0 +1,73 @@
+package graphview
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+)
+
+type GraphContentEncoder interface {
+	Encode(io.Writer, []*Graph) error
+}
+
+// JsonEncoder 输出 JsonDecoder 可读取的 graphList 格式
+type JsonEncoder struct {
+	Indent string
+}
+
+func (j *JsonEncoder) Encode(w io.Writer, graphs []*Graph) error {
+	h, err := encodeList(graphs)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	if j.Indent != "" {
+		enc.SetIndent("", j.Indent)
+	}
+	return enc.Encode(h)
+}
+
+func encodeList(graphs []*Graph) (graphList, error) {
+	h := graphList{
+		Version:   CurrentVersion,
+		GraphList: make([]graphJsonContent, 0, len(graphs)),
+	}
+	for _, g := range graphs {
+		if g == nil {
+			return h, fmt.Errorf("%w,nil graph", ErrIllegalContent)
+		}
+		h.GraphList = append(h.GraphList, encodeRow(g))
+	}
+	return h, nil
+}
+
+func encodeRow(g *Graph) graphJsonContent {
+	row := graphJsonContent{
+		GraphName: g.GraphName,
+	}
+	for _, n := range g.Nodes() {
+		depend := g.GetDepend(n.Id)
+		sort.Slice(depend, func(i, j int) bool {
+			return depend[i] < depend[j]
+		})
+		names := make([]string, 0, len(depend))
+		for _, id := range depend {
+			names = append(names, g.FindNode(id).Name)
+		}
+		row.Nodes = append(row.Nodes, &nodeJsonContent{
+			Id:           n.Id,
+			Name:         n.Name,
+			DependOnName: names,
+			Attrs:        maps.Clone(n.Attrs),
+			Handler:      n.Handler,
+			Params:       maps.Clone(n.Params),
+		})
+	}
+	return row
+}
+
+var (
+	_ GraphContentEncoder = &JsonEncoder{}
+)
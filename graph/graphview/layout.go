@@ -0,0 +1,322 @@
+package graphview
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+type Point struct {
+	X, Y float64
+}
+
+// NodeBox 节点在画布中的位置, X/Y 为中心点
+type NodeBox struct {
+	Node  *Node
+	Label string
+	X, Y  float64
+	W, H  float64
+}
+
+type EdgePath struct {
+	From, To *Node
+	Points   []Point
+}
+
+// Layout 分层布局的结果
+type Layout struct {
+	Width, Height float64
+	Layers        int
+	Nodes         []*NodeBox
+	Edges         []*EdgePath
+}
+
+const (
+	layoutCharWidth  = 7.5
+	layoutLineHeight = 16.0
+	layoutPadding    = 12.0
+	layoutMinWidth   = 60.0
+	layoutNodeGap    = 30.0
+	layoutLayerGap   = 60.0
+	layoutMargin     = 20.0
+	layoutDummyWidth = 8.0
+	layoutSweeps     = 8
+)
+
+type layoutItem struct {
+	box     *NodeBox
+	breadth float64 // 层内方向的尺寸
+	depth   float64 // 层间方向的尺寸
+	index   int
+	pos     float64
+	up      []*layoutItem
+	down    []*layoutItem
+}
+
+// LayeredLayout 计算 Sugiyama 风格的分层布局:
+// 最长路径分层, 长边插入虚拟节点, 重心法减少交叉, 再按邻居位置对齐坐标
+func LayeredLayout(g *graph.Graph, opts ...RenderOption) (*Layout, error) {
+	c := newRenderConfig(opts...)
+	levels, err := g.Levels()
+	if err != nil {
+		return nil, err
+	}
+	horizontal := c.direction == LeftRight || c.direction == RightLeft
+	rank := make(map[uint32]int)
+	items := make(map[uint32]*layoutItem)
+	layers := make([][]*layoutItem, len(levels))
+	for l, level := range levels {
+		for _, n := range level {
+			label := c.nodeLabel(n)
+			lines := strings.Split(label, "\n")
+			longest := 0
+			for _, line := range lines {
+				if w := len([]rune(line)); w > longest {
+					longest = w
+				}
+			}
+			box := &NodeBox{
+				Node:  n,
+				Label: label,
+				W:     math.Max(layoutMinWidth, float64(longest)*layoutCharWidth+2*layoutPadding),
+				H:     float64(len(lines))*layoutLineHeight + 2*layoutPadding,
+			}
+			it := &layoutItem{box: box, breadth: box.W, depth: box.H}
+			if horizontal {
+				it.breadth, it.depth = box.H, box.W
+			}
+			rank[n.Id] = l
+			items[n.Id] = it
+			layers[l] = append(layers[l], it)
+		}
+	}
+	// 跨越多层的边拆分为经过虚拟节点的链
+	type chain struct {
+		from, to *Node
+		items    []*layoutItem
+	}
+	var chains []chain
+	for _, e := range sortedEdges(g) {
+		from, to := g.FindNode(e[0]), g.FindNode(e[1])
+		prev := items[from.Id]
+		ch := chain{from: from, to: to, items: []*layoutItem{prev}}
+		for l := rank[from.Id] + 1; l < rank[to.Id]; l++ {
+			dummy := &layoutItem{breadth: layoutDummyWidth}
+			layers[l] = append(layers[l], dummy)
+			prev.down = append(prev.down, dummy)
+			dummy.up = append(dummy.up, prev)
+			ch.items = append(ch.items, dummy)
+			prev = dummy
+		}
+		last := items[to.Id]
+		prev.down = append(prev.down, last)
+		last.up = append(last.up, prev)
+		ch.items = append(ch.items, last)
+		chains = append(chains, ch)
+	}
+
+	orderLayers(layers)
+	assignPositions(layers)
+
+	// 层间坐标
+	layout := &Layout{Layers: len(layers)}
+	along := layoutMargin
+	var maxAcross float64
+	for _, layer := range layers {
+		var depth float64
+		for _, it := range layer {
+			depth = math.Max(depth, it.depth)
+			maxAcross = math.Max(maxAcross, it.pos+it.breadth/2)
+		}
+		for _, it := range layer {
+			if it.box == nil {
+				it.box = &NodeBox{}
+			}
+			if horizontal {
+				it.box.X, it.box.Y = along+depth/2, it.pos
+			} else {
+				it.box.X, it.box.Y = it.pos, along+depth/2
+			}
+		}
+		along += depth + layoutLayerGap
+	}
+	// 空图没有层, 画布只保留边距
+	along = math.Max(along-layoutLayerGap+layoutMargin, 2*layoutMargin)
+	across := math.Max(maxAcross+layoutMargin, 2*layoutMargin)
+	if horizontal {
+		layout.Width, layout.Height = along, across
+	} else {
+		layout.Width, layout.Height = across, along
+	}
+	flip := func(p *Point) {
+		switch c.direction {
+		case BottomTop:
+			p.Y = layout.Height - p.Y
+		case RightLeft:
+			p.X = layout.Width - p.X
+		}
+	}
+	for _, n := range g.Nodes() {
+		box := items[n.Id].box
+		p := Point{box.X, box.Y}
+		flip(&p)
+		box.X, box.Y = p.X, p.Y
+		layout.Nodes = append(layout.Nodes, box)
+	}
+	for _, ch := range chains {
+		path := &EdgePath{From: ch.from, To: ch.to}
+		for _, it := range ch.items {
+			p := Point{it.box.X, it.box.Y}
+			if it.box.Node == nil {
+				flip(&p)
+			}
+			path.Points = append(path.Points, p)
+		}
+		path.Points[0] = borderPoint(items[ch.from.Id].box, path.Points[1])
+		last := len(path.Points) - 1
+		path.Points[last] = borderPoint(items[ch.to.Id].box, path.Points[last-1])
+		layout.Edges = append(layout.Edges, path)
+	}
+	return layout, nil
+}
+
+// orderLayers 交替向下/向上按邻居的平均位置(重心)排序, 减少边交叉
+func orderLayers(layers [][]*layoutItem) {
+	reindex := func(layer []*layoutItem) {
+		for i, it := range layer {
+			it.index = i
+		}
+	}
+	for _, layer := range layers {
+		reindex(layer)
+	}
+	for sweep := 0; sweep < layoutSweeps; sweep++ {
+		down := sweep%2 == 0
+		for k := 1; k < len(layers); k++ {
+			l := k
+			if !down {
+				l = len(layers) - 1 - k
+			}
+			layer := layers[l]
+			for _, it := range layer {
+				neighbors := it.up
+				if !down {
+					neighbors = it.down
+				}
+				if len(neighbors) == 0 {
+					it.pos = float64(it.index)
+					continue
+				}
+				var sum float64
+				for _, v := range neighbors {
+					sum += float64(v.index)
+				}
+				it.pos = sum / float64(len(neighbors))
+			}
+			sort.SliceStable(layer, func(i, j int) bool {
+				return layer[i].pos < layer[j].pos
+			})
+			reindex(layer)
+		}
+	}
+}
+
+// assignPositions 在保持层内顺序和最小间距的前提下, 把节点移向相邻层邻居的平均位置
+func assignPositions(layers [][]*layoutItem) {
+	var widest float64
+	for _, layer := range layers {
+		x := layoutMargin
+		for _, it := range layer {
+			it.pos = x + it.breadth/2
+			x += it.breadth + layoutNodeGap
+		}
+		widest = math.Max(widest, x-layoutNodeGap)
+	}
+	// 各层居中
+	for _, layer := range layers {
+		if len(layer) == 0 {
+			continue
+		}
+		last := layer[len(layer)-1]
+		shift := (widest - (last.pos + last.breadth/2)) / 2
+		for _, it := range layer {
+			it.pos += shift
+		}
+	}
+	for sweep := 0; sweep < layoutSweeps; sweep++ {
+		down := sweep%2 == 0
+		for k := 0; k < len(layers); k++ {
+			l := k
+			if !down {
+				l = len(layers) - 1 - k
+			}
+			layer := layers[l]
+			desired := make([]float64, len(layer))
+			for i, it := range layer {
+				neighbors := it.up
+				if !down {
+					neighbors = it.down
+				}
+				desired[i] = it.pos
+				if len(neighbors) > 0 {
+					var sum float64
+					for _, v := range neighbors {
+						sum += v.pos
+					}
+					desired[i] = sum / float64(len(neighbors))
+				}
+			}
+			// 从左到右保证间距, 再从右到左回拉, 取两次结果的平均
+			left := make([]float64, len(layer))
+			for i, it := range layer {
+				left[i] = desired[i]
+				if i > 0 {
+					left[i] = math.Max(left[i], left[i-1]+(layer[i-1].breadth+it.breadth)/2+layoutNodeGap)
+				}
+			}
+			right := make([]float64, len(layer))
+			for i := len(layer) - 1; i >= 0; i-- {
+				right[i] = desired[i]
+				if i < len(layer)-1 {
+					right[i] = math.Min(right[i], right[i+1]-(layer[i+1].breadth+layer[i].breadth)/2-layoutNodeGap)
+				}
+			}
+			for i, it := range layer {
+				it.pos = (left[i] + right[i]) / 2
+				if i > 0 {
+					it.pos = math.Max(it.pos, layer[i-1].pos+(layer[i-1].breadth+it.breadth)/2+layoutNodeGap)
+				}
+			}
+		}
+	}
+	// 平移到画布边距内
+	minPos := math.Inf(1)
+	for _, layer := range layers {
+		for _, it := range layer {
+			minPos = math.Min(minPos, it.pos-it.breadth/2)
+		}
+	}
+	for _, layer := range layers {
+		for _, it := range layer {
+			it.pos += layoutMargin - minPos
+		}
+	}
+}
+
+// borderPoint 返回从节点中心指向 toward 的射线与节点边框的交点
+func borderPoint(box *NodeBox, toward Point) Point {
+	dx, dy := toward.X-box.X, toward.Y-box.Y
+	if dx == 0 && dy == 0 {
+		return Point{box.X, box.Y}
+	}
+	scale := math.Inf(1)
+	if dx != 0 {
+		scale = math.Min(scale, box.W/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		scale = math.Min(scale, box.H/2/math.Abs(dy))
+	}
+	return Point{box.X + dx*scale, box.Y + dy*scale}
+}
@@ -0,0 +1,68 @@
+package graphview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// GetMermaidGraphDesc 输出 mermaid flowchart
+func GetMermaidGraphDesc(g *graph.Graph, opts ...RenderOption) (string, error) {
+	c := newRenderConfig(opts...)
+	var buf strings.Builder
+	buf.WriteString("flowchart ")
+	buf.WriteString(mermaidDirection(c.direction))
+	buf.WriteString("\n")
+	for i, group := range c.groups(g) {
+		depth := 1
+		if group.name != "" {
+			indent(&buf, depth)
+			buf.WriteString(fmt.Sprintf("subgraph group%d [\"%s\"]\n", i, mermaidEscape(group.name)))
+			depth++
+		}
+		for _, n := range group.nodes {
+			indent(&buf, depth)
+			buf.WriteString(fmt.Sprintf("%s[\"%s\"]\n", mermaidId(n), mermaidEscape(c.nodeLabel(n))))
+		}
+		if group.name != "" {
+			indent(&buf, 1)
+			buf.WriteString("end\n")
+		}
+	}
+	for _, v := range sortedEdges(g) {
+		indent(&buf, 1)
+		buf.WriteString(fmt.Sprintf("%s --> %s\n", mermaidId(g.FindNode(v[0])), mermaidId(g.FindNode(v[1]))))
+	}
+	for _, status := range c.usedStatus(g) {
+		indent(&buf, 1)
+		buf.WriteString(mermaidClassDef(status) + "\n")
+	}
+	for _, n := range g.Nodes() {
+		if run, ok := c.nodeRun(n); ok {
+			indent(&buf, 1)
+			buf.WriteString(fmt.Sprintf("class %s %s\n", mermaidId(n), run.Status))
+		}
+	}
+	return buf.String(), nil
+}
+
+func mermaidId(n *Node) string {
+	return fmt.Sprintf("n%d", n.Id)
+}
+
+func mermaidDirection(d Direction) string {
+	if d == TopBottom {
+		return "TD"
+	}
+	return string(d)
+}
+
+var mermaidReplacer = strings.NewReplacer(
+	`"`, "#quot;",
+	"\n", "<br/>",
+)
+
+func mermaidEscape(s string) string {
+	return mermaidReplacer.Replace(s)
+}
@@ -0,0 +1,169 @@
+package graphview
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CurrentVersion 是 graphList 格式的当前版本, 不带 version 字段的内容视为版本 0
+const CurrentVersion = 1
+
+var (
+	ErrUnsupportedVersion = fmt.Errorf("unsupported graph content version")
+)
+
+// ContentErrors 汇总一次解析中发现的全部问题
+type ContentErrors []error
+
+func (e ContentErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("%d problems found:", len(e)))
+	for _, v := range e {
+		buf.WriteString("\n  ")
+		buf.WriteString(v.Error())
+	}
+	return buf.String()
+}
+func (e ContentErrors) Unwrap() []error {
+	return e
+}
+
+// Migration 把 version 为 from 的文档原地升级到 from+1
+type Migration func(doc map[string]any) error
+
+var migrations = map[int]Migration{
+	0: migrateV0,
+}
+
+// migrateV0 兼容早期的单图格式:
+// {"graphName": "graph", "graphType": "dag", "nodes": [...]}
+func migrateV0(doc map[string]any) error {
+	if _, ok := doc["graphList"]; !ok {
+		if _, ok := doc["nodes"]; ok {
+			row := make(map[string]any)
+			for _, key := range []string{"graphName", "graphId", "graphType", "nodes"} {
+				if v, ok := doc[key]; ok {
+					row[key] = v
+					delete(doc, key)
+				}
+			}
+			doc["graphList"] = []any{row}
+		}
+	}
+	list, _ := doc["graphList"].([]any)
+	for i, v := range list {
+		row, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if typ, ok := row["graphType"]; ok {
+			if typ != "dag" {
+				return fmt.Errorf("%w,graphList[%d] graphType %v not supported", ErrIllegalContent, i, typ)
+			}
+			delete(row, "graphType")
+		}
+	}
+	return nil
+}
+
+// Migrate 把文档升级到 CurrentVersion
+func Migrate(doc map[string]any) error {
+	v, err := documentVersion(doc)
+	if err != nil {
+		return err
+	}
+	if v > CurrentVersion {
+		return fmt.Errorf("%w %d,current %d", ErrUnsupportedVersion, v, CurrentVersion)
+	}
+	for ; v < CurrentVersion; v++ {
+		fn, ok := migrations[v]
+		if !ok {
+			return fmt.Errorf("%w %d,no migration", ErrUnsupportedVersion, v)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	doc["version"] = CurrentVersion
+	return nil
+}
+
+func documentVersion(doc map[string]any) (int, error) {
+	switch v := doc["version"].(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%w,version %v", ErrIllegalContent, v)
+		}
+		return int(n), nil
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%w,version %v", ErrIllegalContent, v)
+	}
+}
+
+// checkUnknownFields 一次性列出所有未知字段, 字段集合取自结构体的 json tag
+func checkUnknownFields(doc map[string]any) ContentErrors {
+	var errs ContentErrors
+	walkFields(doc, reflect.TypeOf(graphList{}), "", &errs)
+	return errs
+}
+
+func walkFields(v any, t reflect.Type, at string, errs *ContentErrors) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" {
+				name = f.Name
+			}
+			fields[name] = f.Type
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			val := obj[key]
+			where := key
+			if at != "" {
+				where = at + "." + key
+			}
+			ft, ok := fields[key]
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("%w,unknown field %s", ErrIllegalContent, where))
+				continue
+			}
+			walkFields(val, ft, where, errs)
+		}
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			return
+		}
+		for i, val := range arr {
+			walkFields(val, t.Elem(), fmt.Sprintf("%s[%d]", at, i), errs)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package graphview
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// GetPlantUMLGraphDesc 输出 plantuml activity 图 (legacy 语法, 支持任意 DAG)
+func GetPlantUMLGraphDesc(g *graph.Graph, opts ...RenderOption) (string, error) {
+	c := newRenderConfig(opts...)
+	levels, err := g.Levels()
+	if err != nil {
+		return "", err
+	}
+	groupOf := func(n *Node) string {
+		return strings.Join(c.groupPath(n), "/")
+	}
+	var buf strings.Builder
+	buf.WriteString("@startuml\n")
+	if c.direction == LeftRight || c.direction == RightLeft {
+		buf.WriteString("left to right direction\n")
+	}
+	declared := make(map[uint32]bool)
+	ref := func(n *Node) string {
+		if declared[n.Id] {
+			return plantumlId(n)
+		}
+		declared[n.Id] = true
+		return fmt.Sprintf("\"%s\" as %s", plantumlEscape(c.label(n)), plantumlId(n))
+	}
+	// 按拓扑顺序声明节点, 保证前驱先于后继出现; 同层节点按分组聚在一起
+	current := ""
+	for _, level := range levels {
+		sort.SliceStable(level, func(i, j int) bool {
+			return groupOf(level[i]) < groupOf(level[j])
+		})
+		for _, n := range level {
+			if group := groupOf(n); group != current {
+				if current != "" {
+					buf.WriteString("}\n")
+				}
+				if group != "" {
+					buf.WriteString(fmt.Sprintf("partition \"%s\" {\n", plantumlEscape(group)))
+				}
+				current = group
+			}
+			depth := 0
+			if current != "" {
+				depth = 1
+			}
+			depend := g.GetDepend(n.Id)
+			sort.Slice(depend, func(i, j int) bool {
+				return depend[i] < depend[j]
+			})
+			if len(depend) == 0 {
+				indent(&buf, depth)
+				buf.WriteString("(*) --> " + ref(n) + "\n")
+			}
+			for _, id := range depend {
+				indent(&buf, depth)
+				buf.WriteString(plantumlId(g.FindNode(id)) + " --> " + ref(n) + "\n")
+			}
+		}
+	}
+	if current != "" {
+		buf.WriteString("}\n")
+	}
+	for _, n := range g.Nodes() {
+		if len(g.Next[n.Id]) == 0 {
+			buf.WriteString(plantumlId(n) + " --> (*)\n")
+		}
+	}
+	buf.WriteString("@enduml\n")
+	return buf.String(), nil
+}
+
+func plantumlId(n *Node) string {
+	return fmt.Sprintf("n%d", n.Id)
+}
+
+var plantumlReplacer = strings.NewReplacer(
+	`"`, "'",
+	"\n", `\n`,
+)
+
+func plantumlEscape(s string) string {
+	return plantumlReplacer.Replace(s)
+}
@@ -0,0 +1,150 @@
+package graphview
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+type Direction string
+
+const (
+	TopBottom Direction = "TB"
+	BottomTop Direction = "BT"
+	LeftRight Direction = "LR"
+	RightLeft Direction = "RL"
+)
+
+// GroupAttr 是默认的分组属性名
+const GroupAttr = "group"
+
+// SubDagAttr 是默认的子 dag 属性名, 值为 '/' 分隔的子 dag 路径, 如 "etl/load"
+const SubDagAttr = "subdag"
+
+type RenderOption func(c *renderConfig)
+
+type renderConfig struct {
+	direction Direction
+	label     func(n *Node) string
+	group     func(n *Node) string
+	subDag    func(n *Node) string
+	runs      map[string]graph.NodeRun
+	nodeAttrs func(n *Node) map[string]string
+	edgeAttrs func(from, to *Node) map[string]string
+}
+
+func newRenderConfig(opts ...RenderOption) *renderConfig {
+	c := &renderConfig{
+		direction: TopBottom,
+		label: func(n *Node) string {
+			return n.Name
+		},
+	}
+	for _, fn := range opts {
+		fn(c)
+	}
+	return c
+}
+
+func WithDirection(d Direction) RenderOption {
+	return func(c *renderConfig) {
+		c.direction = d
+	}
+}
+
+// WithNodeLabel 自定义节点显示文本, 默认使用节点名
+func WithNodeLabel(fn func(n *Node) string) RenderOption {
+	return func(c *renderConfig) {
+		c.label = fn
+	}
+}
+
+// WithGroupBy 按返回值对节点分组, 空字符串表示不分组
+func WithGroupBy(fn func(n *Node) string) RenderOption {
+	return func(c *renderConfig) {
+		c.group = fn
+	}
+}
+
+// WithGroupAttr 按节点属性分组
+func WithGroupAttr(key string) RenderOption {
+	return WithGroupBy(func(n *Node) string {
+		return n.Attr(key)
+	})
+}
+
+// WithSubDagAttr 按节点属性中的子 dag 路径分组. DOT 输出为嵌套的 cluster, 同时按属性分组时
+// 属性分组在子 dag 内层; 其他格式按完整路径平铺分组
+func WithSubDagAttr(key string) RenderOption {
+	return func(c *renderConfig) {
+		c.subDag = func(n *Node) string {
+			return n.Attr(key)
+		}
+	}
+}
+
+// groupPath 返回节点所在的各级分组, 子 dag 在外, 属性分组在最内层
+func (c *renderConfig) groupPath(n *Node) []string {
+	var ret []string
+	if c.subDag != nil {
+		for _, v := range strings.Split(c.subDag(n), "/") {
+			if v != "" {
+				ret = append(ret, v)
+			}
+		}
+	}
+	if c.group != nil {
+		if name := c.group(n); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+type nodeGroup struct {
+	name  string
+	nodes []*Node
+}
+
+// groups 返回排序后的分组, 未分组的节点在第一个名字为空的分组中
+func (c *renderConfig) groups(g *graph.Graph) []nodeGroup {
+	index := make(map[string]int)
+	ret := []nodeGroup{{}}
+	for _, n := range g.Nodes() {
+		name := strings.Join(c.groupPath(n), "/")
+		if name == "" {
+			ret[0].nodes = append(ret[0].nodes, n)
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(ret)
+			index[name] = i
+			ret = append(ret, nodeGroup{name: name})
+		}
+		ret[i].nodes = append(ret[i].nodes, n)
+	}
+	sort.SliceStable(ret[1:], func(i, j int) bool {
+		return ret[1+i].name < ret[1+j].name
+	})
+	return ret
+}
+
+// sortedEdges 按 (from, to) 排序, 保证输出稳定
+func sortedEdges(g *graph.Graph) [][]uint32 {
+	arr := g.GetEdgeList()
+	sort.Slice(arr, func(i, j int) bool {
+		if arr[i][0] != arr[j][0] {
+			return arr[i][0] < arr[j][0]
+		}
+		return arr[i][1] < arr[j][1]
+	})
+	return arr
+}
+
+func indent(buf *strings.Builder, depth int) {
+	for i := 0; i < depth; i++ {
+		buf.WriteString("    ")
+	}
+}
@@ -0,0 +1,70 @@
+package graphview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func newRenderGraph() *graph.Graph {
+	return graph.NewGraph(
+		graph.WithNodes(
+			(&graph.Node{Name: "start"}).SetAttr(GroupAttr, "load"),
+			(&graph.Node{Name: "fetch \"db\""}).SetAttr(GroupAttr, "load"),
+			&graph.Node{Name: "merge"},
+			&graph.Node{Name: "alone"},
+		),
+		graph.WithDependOn("fetch \"db\"", "start"),
+		graph.WithDependOn("merge", "start", "fetch \"db\""),
+	)
+}
+
+func TestMermaidGraphDesc(t *testing.T) {
+	s, err := GetMermaidGraphDesc(newRenderGraph(),
+		WithDirection(LeftRight),
+		WithGroupAttr(GroupAttr),
+		WithNodeLabel(func(n *Node) string {
+			return strings.ToUpper(n.Name)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `flowchart LR
+    n3["MERGE"]
+    n4["ALONE"]
+    subgraph group1 ["load"]
+        n1["START"]
+        n2["FETCH #quot;DB#quot;"]
+    end
+    n1 --> n2
+    n1 --> n3
+    n2 --> n3
+`
+	if s != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", s, expect)
+	}
+}
+
+func TestPlantUMLGraphDesc(t *testing.T) {
+	s, err := GetPlantUMLGraphDesc(newRenderGraph(), WithGroupAttr(GroupAttr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `@startuml
+(*) --> "alone" as n4
+partition "load" {
+    (*) --> "start" as n1
+    n1 --> "fetch 'db'" as n2
+}
+n1 --> "merge" as n3
+n2 --> n3
+n3 --> (*)
+n4 --> (*)
+@enduml
+`
+	if s != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", s, expect)
+	}
+}
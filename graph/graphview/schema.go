@@ -0,0 +1,15 @@
+package graphview
+
+import (
+	_ "embed"
+)
+
+//go:embed schema/graph.schema.json
+var graphSchema []byte
+
+// JsonSchema 返回 graphList 格式的 JSON Schema (draft 2020-12)
+func JsonSchema() []byte {
+	ret := make([]byte, len(graphSchema))
+	copy(ret, graphSchema)
+	return ret
+}
@@ -2,30 +2,188 @@ package graphview
 
 import (
 	"fmt"
+	"maps"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/opengeektech/go-dag/graph"
 )
 
-func GetDotGraphDesc(g *graph.Graph) (string,error) {
+// DotAttrPrefix 节点属性中以此为前缀的键作为 DOT 属性输出, 如 "dot.shape": "box"
+const DotAttrPrefix = "dot."
+
+// WithNodeAttrs 追加节点的 DOT 属性, 覆盖同名的节点元数据属性
+func WithNodeAttrs(fn func(n *Node) map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		c.nodeAttrs = fn
+	}
+}
+
+// WithEdgeAttrs 设置边的 DOT 属性
+func WithEdgeAttrs(fn func(from, to *Node) map[string]string) RenderOption {
+	return func(c *renderConfig) {
+		c.edgeAttrs = fn
+	}
+}
+
+// GetDotGraphDesc 输出 graphviz DOT, 节点和属性均按固定顺序输出, 便于比较差异
+func GetDotGraphDesc(g *graph.Graph, opts ...RenderOption) (string, error) {
+	c := newRenderConfig(opts...)
 	var buf strings.Builder
-	buf.WriteString("digraph G {\n")
-	arr := g.GetEdgeList()
-	for _,v := range arr {
+	name := g.GraphName
+	if name == "" {
+		name = "G"
+	}
+	buf.WriteString("digraph " + dotID(name) + " {\n")
+	if c.direction != TopBottom {
+		buf.WriteString("    rankdir=" + string(c.direction) + ";\n")
+	}
+	seq := 0
+	writeDotCluster(&buf, c, newDotCluster(c, g), 1, &seq)
+	for _, v := range sortedEdges(g) {
 		f := g.FindNode(v[0])
 		t := g.FindNode(v[1])
-		buf.WriteString(" ")
-		buf.WriteString(fmt.Sprintf("%s -> %s",f.Name,t.Name))
+		indent(&buf, 1)
+		buf.WriteString(dotID(f.Name) + " -> " + dotID(t.Name))
+		if c.edgeAttrs != nil {
+			buf.WriteString(dotAttrList(c.edgeAttrs(f, t)))
+		}
 		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	return buf.String(), nil
+}
 
+// dotCluster 按子 dag 和属性分组嵌套的 cluster, 根节点为整个图
+type dotCluster struct {
+	name     string
+	nodes    []*Node
+	children map[string]*dotCluster
+}
+
+func newDotCluster(c *renderConfig, g *graph.Graph) *dotCluster {
+	root := &dotCluster{}
+	for _, n := range g.Nodes() {
+		cur := root
+		for _, name := range c.groupPath(n) {
+			next, ok := cur.children[name]
+			if !ok {
+				if cur.children == nil {
+					cur.children = make(map[string]*dotCluster)
+				}
+				next = &dotCluster{name: name}
+				cur.children[name] = next
+			}
+			cur = next
+		}
+		cur.nodes = append(cur.nodes, n)
 	}
-	buf.WriteString("}")
-	return buf.String(),nil
+	return root
 }
-func GetDotGraphDescLink(g *graph.Graph) string {
-	s,_ := GetDotGraphDesc(g)
+
+// writeDotCluster 先输出直接属于该层的节点, 再按名字顺序输出子 cluster, seq 为 cluster 编号
+func writeDotCluster(buf *strings.Builder, c *renderConfig, cl *dotCluster, depth int, seq *int) {
+	for _, n := range cl.nodes {
+		indent(buf, depth)
+		buf.WriteString(dotID(n.Name))
+		buf.WriteString(dotAttrList(c.dotNodeAttrs(n)))
+		buf.WriteString(";\n")
+	}
+	names := make([]string, 0, len(cl.children))
+	for k := range cl.children {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		*seq++
+		indent(buf, depth)
+		buf.WriteString(fmt.Sprintf("subgraph cluster_%d {\n", *seq))
+		indent(buf, depth+1)
+		buf.WriteString("label=" + dotQuote(name) + ";\n")
+		writeDotCluster(buf, c, cl.children[name], depth+1, seq)
+		indent(buf, depth)
+		buf.WriteString("}\n")
+	}
+}
+
+func (c *renderConfig) dotNodeAttrs(n *Node) map[string]string {
+	attrs := make(map[string]string)
+	for k, v := range n.Attrs {
+		if key, ok := strings.CutPrefix(k, DotAttrPrefix); ok && key != "" {
+			attrs[key] = v
+		}
+	}
+	if c.nodeAttrs != nil {
+		maps.Copy(attrs, c.nodeAttrs(n))
+	}
+	if run, ok := c.nodeRun(n); ok {
+		st := statusStyles[run.Status]
+		attrs["style"] = "filled"
+		if st.dashed {
+			attrs["style"] += ",dashed"
+		}
+		attrs["fillcolor"] = st.fill
+		attrs["color"] = st.stroke
+	}
+	if label := c.nodeLabel(n); label != n.Name {
+		attrs["label"] = label
+	}
+	return attrs
+}
+
+func dotAttrList(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	buf.WriteString(" [")
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(dotID(k) + "=" + dotQuote(attrs[k]))
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+var (
+	dotPlainID  = regexp.MustCompile(`^[A-Za-z_\x80-\x{10FFFF}][A-Za-z0-9_\x80-\x{10FFFF}]*$`)
+	dotKeywords = map[string]struct{}{
+		"node": {}, "edge": {}, "graph": {}, "digraph": {}, "subgraph": {}, "strict": {},
+	}
+)
+
+// dotID 合法的标识符原样输出, 其余(含空格, '-', ':' 等)加引号
+func dotID(s string) string {
+	if dotPlainID.MatchString(s) {
+		if _, ok := dotKeywords[strings.ToLower(s)]; !ok {
+			return s
+		}
+	}
+	return dotQuote(s)
+}
+
+var dotReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
+
+func GetDotGraphDescLink(g *graph.Graph, opts ...RenderOption) string {
+	s, _ := GetDotGraphDesc(g, opts...)
 	ns := url.PathEscape(s)
 	s2 := `https://dreampuf.github.io/GraphvizOnline/?engine=dot#` + ns
 	return s2
-}
\ No newline at end of file
+}
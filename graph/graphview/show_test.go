@@ -3,7 +3,12 @@ package graphview
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opengeektech/go-dag/graph"
 )
 
 func TestShowDotGraph(t *testing.T) {
@@ -25,3 +30,157 @@ func TestShowDotGraph(t *testing.T) {
 	t.Log("\n"+s)
 	t.Log("\n"+GetDotGraphDescLink(g[0]))
 }
+
+func TestShowDotGraph_RunStatus(t *testing.T) {
+	g := graph.NewGraph(
+		graph.WithNodes(&graph.Node{Name: "a"}, &graph.Node{Name: "b"}, &graph.Node{Name: "c"}),
+		graph.WithDependOn("b", "a"),
+		graph.WithDependOn("c", "b"),
+	)
+	runs := map[string]graph.NodeRun{
+		"a": {Status: graph.StatusSucceeded, Duration: 1500 * time.Microsecond},
+		"b": {Status: graph.StatusFailed, Error: `bad "input"`},
+	}
+	s, err := GetDotGraphDesc(g, WithRunStatus(runs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`fillcolor="#c8e6c9"`,
+		`label="a\nsucceeded 1.5ms"`,
+		`label="b\nfailed\nbad \"input\""`,
+		`style="filled,dashed"`,
+	} {
+		if !strings.Contains(s, expect) {
+			t.Errorf("missing %s in\n%s", expect, s)
+		}
+	}
+	m, err := GetMermaidGraphDesc(g, WithRunStatus(runs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"class n1 succeeded",
+		"class n2 failed",
+		"class n3 not_reached",
+		"classDef not_reached",
+	} {
+		if !strings.Contains(m, expect) {
+			t.Errorf("missing %s in\n%s", expect, m)
+		}
+	}
+	runs["b"] = graph.NodeRun{Status: graph.StatusFailed, Error: strings.Repeat("错误", 50)}
+	s, _ = GetDotGraphDesc(g, WithRunStatus(runs))
+	if !utf8.ValidString(s) || !strings.Contains(s, strings.Repeat("错误", 38)+"错...") {
+		t.Fatal(s)
+	}
+}
+
+func TestShowDotGraph_Writer(t *testing.T) {
+	g := graph.NewGraph(
+		graph.WithNodes(
+			(&graph.Node{Name: "Node:1"}).SetAttr(GroupAttr, "load").SetAttr("dot.shape", "box"),
+			(&graph.Node{Name: "fetch data"}).SetAttr(GroupAttr, "load"),
+			&graph.Node{Name: "a-b"},
+			&graph.Node{Name: "node"},
+			&graph.Node{Name: "alone"},
+		),
+		graph.WithDependOn("fetch data", "Node:1"),
+		graph.WithDependOn("a-b", "fetch data"),
+		graph.WithDependOn("node", "a-b"),
+	)
+	g.GraphName = "demo"
+	s, err := GetDotGraphDesc(g,
+		WithDirection(LeftRight),
+		WithGroupAttr(GroupAttr),
+		WithEdgeAttrs(func(from, to *Node) map[string]string {
+			if to.Name == "node" {
+				return map[string]string{"style": "dashed"}
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `digraph demo {
+    rankdir=LR;
+    "a-b";
+    "node";
+    alone;
+    subgraph cluster_1 {
+        label="load";
+        "Node:1" [shape="box"];
+        "fetch data";
+    }
+    "Node:1" -> "fetch data";
+    "fetch data" -> "a-b";
+    "a-b" -> "node" [style="dashed"];
+}
+`
+	if s != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", s, expect)
+	}
+	again, _ := GetDotGraphDesc(g, WithDirection(LeftRight), WithGroupAttr(GroupAttr))
+	for i := 0; i < 10; i++ {
+		next, _ := GetDotGraphDesc(g, WithDirection(LeftRight), WithGroupAttr(GroupAttr))
+		if next != again {
+			t.Fatal("output not deterministic")
+		}
+	}
+}
+
+func TestShowDotGraph_SubDag(t *testing.T) {
+	g := graph.NewGraph(
+		graph.WithNodes(
+			(&graph.Node{Name: "extract"}).SetAttr(SubDagAttr, "etl"),
+			(&graph.Node{Name: "users"}).SetAttr(SubDagAttr, "etl/load").SetAttr(GroupAttr, "db"),
+			(&graph.Node{Name: "orders"}).SetAttr(SubDagAttr, "etl/load"),
+			(&graph.Node{Name: "notify"}).SetAttr(GroupAttr, "db"),
+			&graph.Node{Name: "report"},
+		),
+		graph.WithDependOn("users", "extract"),
+		graph.WithDependOn("orders", "extract"),
+		graph.WithDependOn("report", "users", "orders"),
+	)
+	s, err := GetDotGraphDesc(g, WithSubDagAttr(SubDagAttr), WithGroupAttr(GroupAttr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `digraph G {
+    report;
+    subgraph cluster_1 {
+        label="db";
+        notify;
+    }
+    subgraph cluster_2 {
+        label="etl";
+        extract;
+        subgraph cluster_3 {
+            label="load";
+            orders;
+            subgraph cluster_4 {
+                label="db";
+                users;
+            }
+        }
+    }
+    extract -> users;
+    extract -> orders;
+    users -> report;
+    orders -> report;
+}
+`
+	if s != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", s, expect)
+	}
+	// 只按子 dag 分组时忽略分组属性, 其他格式按完整路径平铺
+	s, _ = GetDotGraphDesc(g, WithSubDagAttr(SubDagAttr))
+	if strings.Count(s, "subgraph") != 2 || strings.Contains(s, `"db"`) {
+		t.Fatal(s)
+	}
+	m, _ := GetMermaidGraphDesc(g, WithSubDagAttr(SubDagAttr), WithGroupAttr(GroupAttr))
+	if !strings.Contains(m, `["etl/load/db"]`) || !strings.Contains(m, `["etl/load"]`) {
+		t.Fatal(m)
+	}
+}
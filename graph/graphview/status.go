@@ -0,0 +1,92 @@
+package graphview
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// WithRunStatus 按节点运行状态着色, 并在标签中追加耗时和错误信息
+func WithRunStatus(runs map[string]graph.NodeRun) RenderOption {
+	return func(c *renderConfig) {
+		c.runs = runs
+	}
+}
+
+type statusStyle struct {
+	fill   string
+	stroke string
+	dashed bool
+}
+
+var statusStyles = map[graph.NodeStatus]statusStyle{
+	graph.StatusPending:    {fill: "#ffffff", stroke: "#9e9e9e"},
+	graph.StatusRunning:    {fill: "#fff3b0", stroke: "#f9a825"},
+	graph.StatusSucceeded:  {fill: "#c8e6c9", stroke: "#2e7d32"},
+	graph.StatusFailed:     {fill: "#ffcdd2", stroke: "#c62828"},
+	graph.StatusSkipped:    {fill: "#eeeeee", stroke: "#757575"},
+	graph.StatusNotReached: {fill: "#ffffff", stroke: "#bdbdbd", dashed: true},
+}
+
+// nodeRun 没有运行记录的节点视为未执行
+func (c *renderConfig) nodeRun(n *Node) (graph.NodeRun, bool) {
+	if c.runs == nil {
+		return graph.NodeRun{}, false
+	}
+	run, ok := c.runs[n.Name]
+	if !ok {
+		run.Status = graph.StatusNotReached
+	}
+	return run, true
+}
+
+// nodeLabel 返回节点标签, 叠加运行状态时追加状态, 耗时和错误信息
+func (c *renderConfig) nodeLabel(n *Node) string {
+	label := c.label(n)
+	run, ok := c.nodeRun(n)
+	if !ok {
+		return label
+	}
+	label += "\n" + run.Status.String()
+	if run.Duration > 0 {
+		label += " " + run.Duration.Round(time.Microsecond).String()
+	}
+	if run.Error != "" {
+		// 按字符截断, 避免切开多字节的中文
+		msg := []rune(run.Error)
+		if len(msg) > 80 {
+			msg = append(msg[:77], []rune("...")...)
+		}
+		label += "\n" + string(msg)
+	}
+	return label
+}
+
+// usedStatus 返回图中出现的状态, 用于输出样式定义
+func (c *renderConfig) usedStatus(g *graph.Graph) []graph.NodeStatus {
+	seen := make(map[graph.NodeStatus]struct{})
+	for _, n := range g.Nodes() {
+		if run, ok := c.nodeRun(n); ok {
+			seen[run.Status] = struct{}{}
+		}
+	}
+	ret := make([]graph.NodeStatus, 0, len(seen))
+	for k := range seen {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+func mermaidClassDef(s graph.NodeStatus) string {
+	st := statusStyles[s]
+	def := fmt.Sprintf("classDef %s fill:%s,stroke:%s", s, st.fill, st.stroke)
+	if st.dashed {
+		def += ",stroke-dasharray:5 5"
+	}
+	return def
+}
@@ -0,0 +1,122 @@
+package graphview
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+// GetSvgGraphDesc 使用内置的分层布局输出独立的 SVG, 不依赖 graphviz
+func GetSvgGraphDesc(g *graph.Graph, opts ...RenderOption) (string, error) {
+	c := newRenderConfig(opts...)
+	layout, err := LayeredLayout(g, opts...)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica,Arial,sans-serif" font-size="12">`+"\n",
+		layout.Width, layout.Height, layout.Width, layout.Height))
+	buf.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#555"/></marker></defs>` + "\n")
+	writeSvgGroups(&buf, c, g, layout)
+	for _, e := range layout.Edges {
+		var d strings.Builder
+		for i, p := range e.Points {
+			if i == 0 {
+				d.WriteString(fmt.Sprintf("M %.1f %.1f", p.X, p.Y))
+			} else {
+				d.WriteString(fmt.Sprintf(" L %.1f %.1f", p.X, p.Y))
+			}
+		}
+		buf.WriteString(fmt.Sprintf(`<path class="edge" data-from="%s" data-to="%s" d="%s" fill="none" stroke="#555" marker-end="url(#arrow)"/>`+"\n",
+			html.EscapeString(e.From.Name), html.EscapeString(e.To.Name), d.String()))
+	}
+	for _, box := range layout.Nodes {
+		fill, stroke, dash := "#ffffff", "#333333", ""
+		status := ""
+		if run, ok := c.nodeRun(box.Node); ok {
+			st := statusStyles[run.Status]
+			fill, stroke = st.fill, st.stroke
+			if st.dashed {
+				dash = ` stroke-dasharray="5,5"`
+			}
+			status = fmt.Sprintf(` data-status="%s"`, run.Status)
+		}
+		buf.WriteString(fmt.Sprintf(`<g class="node" data-name="%s"%s>`, html.EscapeString(box.Node.Name), status))
+		buf.WriteString(fmt.Sprintf(`<title>%s</title>`, html.EscapeString(box.Label)))
+		buf.WriteString(fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="6" fill="%s" stroke="%s"%s/>`,
+			box.X-box.W/2, box.Y-box.H/2, box.W, box.H, fill, stroke, dash))
+		lines := strings.Split(box.Label, "\n")
+		top := box.Y - float64(len(lines)-1)*layoutLineHeight/2
+		buf.WriteString(fmt.Sprintf(`<text x="%.1f" text-anchor="middle" dominant-baseline="middle">`, box.X))
+		for i, line := range lines {
+			buf.WriteString(fmt.Sprintf(`<tspan x="%.1f" y="%.1f">%s</tspan>`, box.X, top+float64(i)*layoutLineHeight, html.EscapeString(line)))
+		}
+		buf.WriteString("</text></g>\n")
+	}
+	buf.WriteString("</svg>\n")
+	return buf.String(), nil
+}
+
+// writeSvgGroups 为每个分组画出包围其节点的虚线框
+func writeSvgGroups(buf *strings.Builder, c *renderConfig, g *graph.Graph, layout *Layout) {
+	if c.group == nil && c.subDag == nil {
+		return
+	}
+	boxes := make(map[uint32]*NodeBox, len(layout.Nodes))
+	for _, box := range layout.Nodes {
+		boxes[box.Node.Id] = box
+	}
+	for _, group := range c.groups(g) {
+		if group.name == "" {
+			continue
+		}
+		x1, y1 := layout.Width, layout.Height
+		x2, y2 := 0.0, 0.0
+		for _, n := range group.nodes {
+			box := boxes[n.Id]
+			x1, y1 = min(x1, box.X-box.W/2), min(y1, box.Y-box.H/2)
+			x2, y2 = max(x2, box.X+box.W/2), max(y2, box.Y+box.H/2)
+		}
+		pad := layoutMargin / 2
+		buf.WriteString(fmt.Sprintf(`<g class="group"><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="8" fill="#f5f7fa" stroke="#90a4ae" stroke-dasharray="4,3"/><text x="%.1f" y="%.1f" fill="#607d8b">%s</text></g>`+"\n",
+			x1-pad, y1-pad, x2-x1+2*pad, y2-y1+2*pad, x1-pad+4, y1-pad+12, html.EscapeString(group.name)))
+	}
+}
+
+// GetHtmlGraphDesc 输出内嵌 SVG 的独立 HTML 页面, 叠加运行状态时附带图例
+func GetHtmlGraphDesc(g *graph.Graph, opts ...RenderOption) (string, error) {
+	c := newRenderConfig(opts...)
+	svg, err := GetSvgGraphDesc(g, opts...)
+	if err != nil {
+		return "", err
+	}
+	title := g.GraphName
+	if title == "" {
+		title = "graph"
+	}
+	var buf strings.Builder
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	buf.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	buf.WriteString(`<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 24px; color: #263238; }
+.legend span { display: inline-block; margin-right: 16px; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; border: 1px solid; }
+.graph { overflow: auto; border: 1px solid #eceff1; padding: 8px; }
+</style>
+</head>
+<body>
+`)
+	buf.WriteString("<h2>" + html.EscapeString(title) + "</h2>\n")
+	if status := c.usedStatus(g); len(status) > 0 {
+		buf.WriteString(`<div class="legend">`)
+		for _, s := range status {
+			st := statusStyles[s]
+			buf.WriteString(fmt.Sprintf(`<span><i style="background:%s;border-color:%s"></i>%s</span>`, st.fill, st.stroke, s))
+		}
+		buf.WriteString("</div>\n")
+	}
+	buf.WriteString(`<div class="graph">` + "\n" + svg + "</div>\n</body>\n</html>\n")
+	return buf.String(), nil
+}
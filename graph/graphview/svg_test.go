@@ -0,0 +1,102 @@
+package graphview
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/opengeektech/go-dag/graph"
+)
+
+func newLayoutGraph() *graph.Graph {
+	g := graph.NewGraph(
+		graph.WithNodes(
+			&graph.Node{Name: "start"},
+			&graph.Node{Name: "load <users>"},
+			&graph.Node{Name: "load orders"},
+			&graph.Node{Name: "join"},
+			&graph.Node{Name: "report"},
+			&graph.Node{Name: "alone"},
+		),
+		graph.WithDependOn("load <users>", "start"),
+		graph.WithDependOn("load orders", "start"),
+		graph.WithDependOn("join", "load <users>", "load orders"),
+		graph.WithDependOn("report", "join", "start"),
+	)
+	g.GraphName = "layout"
+	return g
+}
+
+func TestLayeredLayout(t *testing.T) {
+	for _, dir := range []Direction{TopBottom, LeftRight, BottomTop, RightLeft} {
+		layout, err := LayeredLayout(newLayoutGraph(), WithDirection(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if layout.Layers != 4 || len(layout.Nodes) != 6 || len(layout.Edges) != 6 {
+			t.Fatal(dir, layout.Layers, len(layout.Nodes), len(layout.Edges))
+		}
+		for i, a := range layout.Nodes {
+			if a.X-a.W/2 < 0 || a.Y-a.H/2 < 0 || a.X+a.W/2 > layout.Width || a.Y+a.H/2 > layout.Height {
+				t.Errorf("%s node %s out of canvas", dir, a.Node.Name)
+			}
+			for _, b := range layout.Nodes[i+1:] {
+				overlapX := a.X-a.W/2 < b.X+b.W/2 && b.X-b.W/2 < a.X+a.W/2
+				overlapY := a.Y-a.H/2 < b.Y+b.H/2 && b.Y-b.H/2 < a.Y+a.H/2
+				if overlapX && overlapY {
+					t.Errorf("%s nodes %s and %s overlap", dir, a.Node.Name, b.Node.Name)
+				}
+			}
+		}
+		for _, e := range layout.Edges {
+			// start -> report 跨越 3 层, 经过 2 个虚拟节点
+			if e.From.Name == "start" && e.To.Name == "report" && len(e.Points) != 4 {
+				t.Errorf("%s long edge points %d", dir, len(e.Points))
+			}
+		}
+	}
+}
+
+func TestLayeredLayout_Empty(t *testing.T) {
+	layout, err := LayeredLayout(graph.NewGraph())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Width != 2*layoutMargin || layout.Height != 2*layoutMargin || len(layout.Nodes) != 0 {
+		t.Fatal(layout.Width, layout.Height)
+	}
+	s, err := GetSvgGraphDesc(graph.NewGraph())
+	if err != nil || !strings.Contains(s, `width="40" height="40" viewBox="0 0 40 40"`) {
+		t.Fatal(err, s)
+	}
+}
+
+func TestSvgGraphDesc(t *testing.T) {
+	runs := map[string]graph.NodeRun{
+		"start":        {Status: graph.StatusSucceeded},
+		"load <users>": {Status: graph.StatusFailed, Error: "timeout"},
+	}
+	s, err := GetSvgGraphDesc(newLayoutGraph(), WithRunStatus(runs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal([]byte(s), new(struct{})); err != nil {
+		t.Fatal("invalid svg ", err)
+	}
+	for _, expect := range []string{
+		`data-name="load &lt;users&gt;" data-status="failed"`,
+		`data-status="not_reached"`,
+		`<tspan`,
+	} {
+		if !strings.Contains(s, expect) {
+			t.Errorf("missing %s", expect)
+		}
+	}
+	page, err := GetHtmlGraphDesc(newLayoutGraph(), WithRunStatus(runs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, "<title>layout</title>") || !strings.Contains(page, `class="legend"`) {
+		t.Error("bad html page")
+	}
+}
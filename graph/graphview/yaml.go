@@ -0,0 +1,45 @@
+package graphview
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YamlDecoder 读取与 JsonDecoder 相同结构的 yaml 内容
+type YamlDecoder struct {
+	JsonDecoder
+}
+
+func (y *YamlDecoder) Decode(r io.Reader) ([]*Graph, error) {
+	var doc map[string]any
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return y.decodeDocument(doc)
+}
+
+type YamlEncoder struct {
+	Indent int
+}
+
+func (y *YamlEncoder) Encode(w io.Writer, graphs []*Graph) error {
+	h, err := encodeList(graphs)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	if y.Indent > 0 {
+		enc.SetIndent(y.Indent)
+	}
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+var (
+	_ GraphContentHelper  = &YamlDecoder{}
+	_ GraphContentEncoder = &YamlEncoder{}
+)
@@ -0,0 +1,45 @@
+package graph
+
+import "sort"
+
+// Stats 图的结构统计
+type Stats struct {
+	Nodes     int      `json:"nodes"`
+	Edges     int      `json:"edges"`
+	Depth     int      `json:"depth"`
+	Width     int      `json:"width"`
+	MaxFanIn  int      `json:"maxFanIn"`
+	MaxFanOut int      `json:"maxFanOut"`
+	Roots     []string `json:"roots"`
+	Leaves    []string `json:"leaves"`
+}
+
+// Stats 统计节点数, 边数, 拓扑层数(Depth), 最大并行宽度(Width)和最大扇入扇出
+func (r *Graph) Stats() (Stats, error) {
+	levels, err := r.Levels()
+	if err != nil {
+		return Stats{}, err
+	}
+	st := Stats{
+		Nodes: len(r.NodeIdMapping),
+		Edges: len(r.GetEdgeList()),
+		Depth: len(levels),
+	}
+	for _, level := range levels {
+		st.Width = max(st.Width, len(level))
+	}
+	for _, n := range r.Nodes() {
+		in, out := len(r.DependOnMap[n.Id]), len(r.Next[n.Id])
+		st.MaxFanIn = max(st.MaxFanIn, in)
+		st.MaxFanOut = max(st.MaxFanOut, out)
+		if in == 0 {
+			st.Roots = append(st.Roots, n.Name)
+		}
+		if out == 0 {
+			st.Leaves = append(st.Leaves, n.Name)
+		}
+	}
+	sort.Strings(st.Roots)
+	sort.Strings(st.Leaves)
+	return st, nil
+}
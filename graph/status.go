@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// NodeStatus 节点在一次运行中的状态
+type NodeStatus uint8
+
+const (
+	StatusPending NodeStatus = iota
+	StatusRunning
+	StatusSucceeded
+	StatusFailed
+	// StatusSkipped 节点没有处理函数或被跳过
+	StatusSkipped
+	// StatusNotReached 运行结束时仍未执行的节点
+	StatusNotReached
+)
+
+var statusNames = [...]string{
+	StatusPending:    "pending",
+	StatusRunning:    "running",
+	StatusSucceeded:  "succeeded",
+	StatusFailed:     "failed",
+	StatusSkipped:    "skipped",
+	StatusNotReached: "not_reached",
+}
+
+func (s NodeStatus) String() string {
+	if int(s) < len(statusNames) {
+		return statusNames[s]
+	}
+	return fmt.Sprintf("NodeStatus(%d)", s)
+}
+func (s NodeStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+func (s *NodeStatus) UnmarshalText(b []byte) error {
+	for i, v := range statusNames {
+		if v == string(b) {
+			*s = NodeStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown node status %q", b)
+}
+
+// NodeRun 记录节点的一次执行结果, 用于可视化等与执行器无关的场景
+type NodeRun struct {
+	Status   NodeStatus    `json:"status"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}